@@ -1,24 +1,47 @@
 package main
 
 import (
+	"errors"
 	"pulumi-eks/internal/command"
+	"pulumi-eks/internal/layout"
 	"pulumi-eks/internal/service"
 	"pulumi-eks/internal/types"
+	"pulumi-eks/internal/validation"
 	cfgreader "pulumi-eks/pkg/read"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	pulumiconfig "github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
 )
 
+const defaultConfigFile = "../config.yaml"
+
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
 		c := &types.Config{}
 
+		configFile, configOverlays, err := stackConfigFiles(ctx)
+		if err != nil {
+			return err
+		}
+
 		config := cfgreader.NewAppConfigReader()
-		err := config.ReadFrom("../config.yaml").Decode(c)
+		err = config.ReadFrom(configFile).
+			Overlay(configOverlays...).
+			Interpolate(cfgreader.NewStackLookup(ctx)).
+			Strict().
+			Decode(c)
 		if err != nil {
 			return err
 		}
 
+		if err := layout.Expand(&c.Spec.Networking); err != nil {
+			return err
+		}
+
+		if err := validation.Validate(c, config.Node(), config.FileOf); err != nil {
+			return err
+		}
+
 		resourceController := command.New()
 
 		networkingService := service.NewNetworking(
@@ -26,6 +49,12 @@ func main() {
 			c.Spec.Networking,
 		)
 
+		customNetworkingService := service.NewCustomNetworking(
+			ctx,
+			c.Spec.Networking,
+			c.Spec.Cluster,
+		)
+
 		autoscalingService := service.NewLaunchTemplate(
 			ctx,
 			c.Spec.Cluster,
@@ -57,18 +86,27 @@ func main() {
 			c.Spec.Cluster,
 		)
 
+		addonsService := service.NewAddons(
+			ctx,
+			c.Spec.Cluster,
+			c.Spec.Addons,
+		)
+
 		extensionsService := service.NewExtensions(
 			ctx,
 			c.Spec.HelmChartsComponentes,
+			config.Secrets(),
 		)
 
 		resourceController.AddCommand(
 			networkingService,
 			clusterService,
+			customNetworkingService,
 			autoscalingService,
 			nodeGroupService,
 			podIdentityService,
 			oidcService,
+			addonsService,
 			extensionsService,
 		)
 
@@ -79,3 +117,22 @@ func main() {
 		)
 	})
 }
+
+// stackConfigFiles reads the spec file and its overlays from the stack
+// configuration (pulumi-eks:configFile and pulumi-eks:configOverlays),
+// falling back to the shared config.yaml.
+func stackConfigFiles(ctx *pulumi.Context) (string, []string, error) {
+	stackConfig := pulumiconfig.New(ctx, "")
+
+	configFile := stackConfig.Get("configFile")
+	if configFile == "" {
+		configFile = defaultConfigFile
+	}
+
+	var configOverlays []string
+	if err := stackConfig.TryObject("configOverlays", &configOverlays); err != nil && !errors.Is(err, pulumiconfig.ErrMissingVar) {
+		return "", nil, err
+	}
+
+	return configFile, configOverlays, nil
+}
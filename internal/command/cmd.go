@@ -2,11 +2,15 @@ package command
 
 import (
 	"errors"
+	"fmt"
 	"pulumi-eks/internal/types"
+	"strings"
 )
 
 type Command interface {
 	Run(*types.InterServicesDependencies) error
+	Produces() []types.Dependency
+	Consumes() []types.Dependency
 }
 
 func New() *CreateCommands {
@@ -21,17 +25,124 @@ func (i *CreateCommands) AddCommand(cmd ...Command) {
 	i.commands = append(i.commands, cmd...)
 }
 
+// RunCommands runs every command after the ones producing what it consumes,
+// regardless of the order they were added in. A command whose producer was
+// skipped fails with a descriptive error instead of reading a nil dependency.
 func (i *CreateCommands) RunCommands(
 	dependency *types.InterServicesDependencies,
 ) error {
-	for _, cmd := range i.commands {
+	ordered, err := i.schedule()
+	if err != nil {
+		return err
+	}
+
+	produced := make(map[types.Dependency]bool)
+	producers := i.producers()
+
+	for _, cmd := range ordered {
+		for _, consumed := range cmd.Consumes() {
+			if !produced[consumed] {
+				return fmt.Errorf(
+					"%w: %s requires %q but %s was skipped",
+					types.ErrDependencyUnavailable, commandName(cmd), consumed, commandName(producers[consumed]),
+				)
+			}
+		}
+
 		if err := cmd.Run(dependency); err != nil {
 			if errors.Is(err, types.ErrNotErrorServiceSkipped) {
 				continue
 			}
 			return err
 		}
+
+		for _, product := range cmd.Produces() {
+			produced[product] = true
+		}
 	}
 
 	return nil
 }
+
+func (i *CreateCommands) producers() map[types.Dependency]Command {
+	producers := make(map[types.Dependency]Command)
+
+	for _, cmd := range i.commands {
+		for _, product := range cmd.Produces() {
+			producers[product] = cmd
+		}
+	}
+
+	return producers
+}
+
+// schedule sorts the commands topologically using Kahn's algorithm. Commands
+// without an ordering constraint between them keep their AddCommand order.
+func (i *CreateCommands) schedule() ([]Command, error) {
+	producers := i.producers()
+
+	for _, cmd := range i.commands {
+		for _, product := range cmd.Produces() {
+			if producer := producers[product]; producer != cmd {
+				return nil, fmt.Errorf("%q is produced by both %s and %s", product, commandName(producer), commandName(cmd))
+			}
+		}
+	}
+
+	inDegree := make(map[Command]int, len(i.commands))
+	dependents := make(map[Command][]Command, len(i.commands))
+
+	for _, cmd := range i.commands {
+		for _, consumed := range cmd.Consumes() {
+			producer, found := producers[consumed]
+			if !found {
+				return nil, fmt.Errorf("%w: %s consumes %q", types.ErrMissingProducer, commandName(cmd), consumed)
+			}
+
+			dependents[producer] = append(dependents[producer], cmd)
+			inDegree[cmd]++
+		}
+	}
+
+	ordered := make([]Command, 0, len(i.commands))
+	scheduled := make(map[Command]bool, len(i.commands))
+
+	for len(ordered) < len(i.commands) {
+		var next Command
+		for _, cmd := range i.commands {
+			if !scheduled[cmd] && inDegree[cmd] == 0 {
+				next = cmd
+				break
+			}
+		}
+
+		if next == nil {
+			return nil, fmt.Errorf("%w: %s", types.ErrDependencyCycle, i.unscheduled(scheduled))
+		}
+
+		scheduled[next] = true
+		ordered = append(ordered, next)
+
+		for _, dependent := range dependents[next] {
+			inDegree[dependent]--
+		}
+	}
+
+	return ordered, nil
+}
+
+func (i *CreateCommands) unscheduled(scheduled map[Command]bool) string {
+	var names []string
+
+	for _, cmd := range i.commands {
+		if !scheduled[cmd] {
+			names = append(names, commandName(cmd))
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func commandName(cmd Command) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", cmd), "*")
+}
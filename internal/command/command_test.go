@@ -0,0 +1,162 @@
+package command
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pulumi-eks/internal/types"
+)
+
+type fakeCommand struct {
+	name     string
+	produces []types.Dependency
+	consumes []types.Dependency
+	err      error
+	ran      *[]string
+}
+
+func (f *fakeCommand) Run(*types.InterServicesDependencies) error {
+	*f.ran = append(*f.ran, f.name)
+	return f.err
+}
+
+func (f *fakeCommand) Produces() []types.Dependency { return f.produces }
+
+func (f *fakeCommand) Consumes() []types.Dependency { return f.consumes }
+
+func newFakeCommands(ran *[]string, commands ...*fakeCommand) *CreateCommands {
+	c := New()
+
+	for _, cmd := range commands {
+		cmd.ran = ran
+		c.AddCommand(cmd)
+	}
+
+	return c
+}
+
+func TestRunCommandsOrdersByDependencies(t *testing.T) {
+	var ran []string
+
+	c := newFakeCommands(&ran,
+		&fakeCommand{name: "nodes", consumes: []types.Dependency{types.ClusterDependency, types.SubnetsDependency}, produces: []types.Dependency{types.NodeGroupsDependency}},
+		&fakeCommand{name: "cluster", consumes: []types.Dependency{types.SubnetsDependency}, produces: []types.Dependency{types.ClusterDependency}},
+		&fakeCommand{name: "networking", produces: []types.Dependency{types.SubnetsDependency}},
+	)
+
+	if err := c.RunCommands(&types.InterServicesDependencies{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"networking", "cluster", "nodes"}
+	if !reflect.DeepEqual(ran, expected) {
+		t.Fatalf("expected %v, got %v", expected, ran)
+	}
+}
+
+func TestRunCommandsKeepsAddOrderWithoutConstraint(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		var ran []string
+
+		c := newFakeCommands(&ran,
+			&fakeCommand{name: "networking", produces: []types.Dependency{types.SubnetsDependency}},
+			&fakeCommand{name: "extensions", consumes: []types.Dependency{types.SubnetsDependency}},
+			&fakeCommand{name: "podIdentity", consumes: []types.Dependency{types.SubnetsDependency}},
+			&fakeCommand{name: "addons", consumes: []types.Dependency{types.SubnetsDependency}},
+		)
+
+		if err := c.RunCommands(&types.InterServicesDependencies{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := []string{"networking", "extensions", "podIdentity", "addons"}
+		if !reflect.DeepEqual(ran, expected) {
+			t.Fatalf("expected %v, got %v", expected, ran)
+		}
+	}
+}
+
+func TestRunCommandsDetectsCycles(t *testing.T) {
+	var ran []string
+
+	c := newFakeCommands(&ran,
+		&fakeCommand{name: "cluster", consumes: []types.Dependency{types.NodeGroupsDependency}, produces: []types.Dependency{types.ClusterDependency}},
+		&fakeCommand{name: "nodes", consumes: []types.Dependency{types.ClusterDependency}, produces: []types.Dependency{types.NodeGroupsDependency}},
+	)
+
+	err := c.RunCommands(&types.InterServicesDependencies{})
+	if !errors.Is(err, types.ErrDependencyCycle) {
+		t.Fatalf("expected a dependency cycle, got %v", err)
+	}
+
+	if len(ran) > 0 {
+		t.Errorf("no command must run on a cycle, ran %v", ran)
+	}
+}
+
+func TestRunCommandsMissingProducer(t *testing.T) {
+	var ran []string
+
+	c := newFakeCommands(&ran,
+		&fakeCommand{name: "nodes", consumes: []types.Dependency{types.LaunchTemplatesDependency}},
+	)
+
+	err := c.RunCommands(&types.InterServicesDependencies{})
+	if !errors.Is(err, types.ErrMissingProducer) || !strings.Contains(err.Error(), `"launchTemplates"`) {
+		t.Fatalf("expected a missing launchTemplates producer, got %v", err)
+	}
+}
+
+func TestRunCommandsDuplicateProducers(t *testing.T) {
+	var ran []string
+
+	c := newFakeCommands(&ran,
+		&fakeCommand{name: "networking", produces: []types.Dependency{types.SubnetsDependency}},
+		&fakeCommand{name: "existing", produces: []types.Dependency{types.SubnetsDependency}},
+	)
+
+	err := c.RunCommands(&types.InterServicesDependencies{})
+	if err == nil || !strings.Contains(err.Error(), `"subnets" is produced by both`) {
+		t.Fatalf("expected a duplicated subnets producer, got %v", err)
+	}
+}
+
+func TestRunCommandsSkippedProducer(t *testing.T) {
+	var ran []string
+
+	c := newFakeCommands(&ran,
+		&fakeCommand{name: "oidc", produces: []types.Dependency{types.OIDCProviderDependency}, err: types.ErrNotErrorServiceSkipped},
+		&fakeCommand{name: "independent"},
+		&fakeCommand{name: "extensions", consumes: []types.Dependency{types.OIDCProviderDependency}},
+	)
+
+	err := c.RunCommands(&types.InterServicesDependencies{})
+	if !errors.Is(err, types.ErrDependencyUnavailable) {
+		t.Fatalf("expected the skipped producer to make the dependency unavailable, got %v", err)
+	}
+
+	expected := []string{"oidc", "independent"}
+	if !reflect.DeepEqual(ran, expected) {
+		t.Fatalf("expected %v to run before failing, got %v", expected, ran)
+	}
+}
+
+func TestRunCommandsStopsOnError(t *testing.T) {
+	var ran []string
+	failure := errors.New("boom")
+
+	c := newFakeCommands(&ran,
+		&fakeCommand{name: "networking", produces: []types.Dependency{types.SubnetsDependency}, err: failure},
+		&fakeCommand{name: "cluster", consumes: []types.Dependency{types.SubnetsDependency}},
+	)
+
+	if err := c.RunCommands(&types.InterServicesDependencies{}); !errors.Is(err, failure) {
+		t.Fatalf("expected the networking error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(ran, []string{"networking"}) {
+		t.Fatalf("expected only networking to run, got %v", ran)
+	}
+}
@@ -0,0 +1,195 @@
+package layout
+
+import (
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"pulumi-eks/internal/types"
+	"pulumi-eks/pkg/cidr"
+	"sort"
+)
+
+const defaultMaxAvailabilityZones = 4
+
+type tier struct {
+	name     string
+	public   bool
+	isolated bool
+	pods     bool
+	spec     types.SubnetTier
+}
+
+// Expand replaces networking.subnetLayout with the subnets it describes, so
+// the rest of the stack only ever deals with networking.subnets.
+//
+// Every tier reserves a block for maxAvailabilityZones subnets, the largest
+// tiers first, and each availability zone takes the slot at its position in
+// that block. Adding an availability zone, up to the maximum, never moves the
+// cidr blocks of the existing ones. The pods tier is laid out the same way in
+// the first secondary cidr block. The ipv6 /64 of every subnet is pinned the
+// same way, from the slot of its tier and of its availability zone.
+func Expand(networking *types.Networking) error {
+	subnetLayout := networking.SubnetLayout
+	if subnetLayout == nil {
+		return nil
+	}
+
+	if len(networking.Subnets) > 0 {
+		return fmt.Errorf("spec.networking: subnets and subnetLayout are mutually exclusive")
+	}
+
+	if len(subnetLayout.AvailabilityZones) == 0 {
+		return fmt.Errorf("spec.networking.subnetLayout.availabilityZones: at least one availability zone is required")
+	}
+
+	vpcPrefix, err := netip.ParsePrefix(networking.CidrBlock)
+	if err != nil || !vpcPrefix.Addr().Is4() {
+		return fmt.Errorf("spec.networking.cidrBlock: %q is not a valid ipv4 cidr block", networking.CidrBlock)
+	}
+	vpcPrefix = vpcPrefix.Masked()
+
+	// the default is fixed, growing it with the availability zones listed
+	// would re-carve every tier and replace all the subnets
+	maxAvailabilityZones := subnetLayout.MaxAvailabilityZones
+	if maxAvailabilityZones == 0 {
+		maxAvailabilityZones = defaultMaxAvailabilityZones
+	}
+
+	if maxAvailabilityZones < len(subnetLayout.AvailabilityZones) {
+		return fmt.Errorf(
+			"spec.networking.subnetLayout.maxAvailabilityZones: %d is less than the %d availability zones listed, raising it re-carves the cidr blocks of every subnet",
+			maxAvailabilityZones, len(subnetLayout.AvailabilityZones),
+		)
+	}
+
+	tiers := layoutTiers(subnetLayout)
+	if len(tiers) == 0 {
+		return fmt.Errorf("spec.networking.subnetLayout: at least one tier (public, private, isolated) is required")
+	}
+
+	l := &layout{
+		networkingName:       networking.Name,
+		availabilityZones:    subnetLayout.AvailabilityZones,
+		maxAvailabilityZones: maxAvailabilityZones,
+		availabilityZoneBits: bits.Len(uint(maxAvailabilityZones - 1)),
+	}
+
+	subnets, err := l.pack(vpcPrefix, tiers)
+	if err != nil {
+		return err
+	}
+
+	if subnetLayout.Pods != nil {
+		if len(networking.SecondaryCidrBlocks) == 0 {
+			return fmt.Errorf("spec.networking.subnetLayout.pods: a secondary cidr block is required to carve the pod subnets from")
+		}
+
+		secondaryPrefix, err := netip.ParsePrefix(networking.SecondaryCidrBlocks[0])
+		if err != nil || !secondaryPrefix.Addr().Is4() {
+			return fmt.Errorf("spec.networking.secondaryCidrBlocks[0]: %q is not a valid ipv4 cidr block", networking.SecondaryCidrBlocks[0])
+		}
+
+		podSubnets, err := l.pack(secondaryPrefix.Masked(), []tier{{name: "pods", pods: true, spec: *subnetLayout.Pods}})
+		if err != nil {
+			return err
+		}
+
+		subnets = append(subnets, podSubnets...)
+	}
+
+	networking.Subnets = subnets
+
+	return nil
+}
+
+type layout struct {
+	networkingName       string
+	availabilityZones    []string
+	maxAvailabilityZones int
+	// slots per tier, rounded up to a power of two to keep the blocks aligned
+	availabilityZoneBits int
+	// tiers packed so far, across the vpc and the secondary cidr blocks
+	packedTiers int
+}
+
+// pack lays the tiers out one after the other in prefix, each tier taking the
+// block of maxAvailabilityZones subnets.
+func (l *layout) pack(prefix netip.Prefix, tiers []tier) ([]types.Subnets, error) {
+	var subnets []types.Subnets
+	var offset uint64
+
+	for _, t := range tiers {
+		tierPath := "spec.networking.subnetLayout." + t.name
+
+		blockBits := t.spec.PrefixLength - l.availabilityZoneBits - prefix.Bits()
+		if t.spec.PrefixLength > 32 || blockBits < 0 {
+			return nil, fmt.Errorf(
+				"%s.prefixLength: /%d subnets for %d availability zones do not fit in %s",
+				tierPath, t.spec.PrefixLength, l.maxAvailabilityZones, prefix,
+			)
+		}
+
+		blockSize := uint64(1) << (32 - t.spec.PrefixLength + l.availabilityZoneBits)
+		if offset+blockSize > uint64(1)<<(32-prefix.Bits()) {
+			return nil, fmt.Errorf(
+				"%s.prefixLength: no room left in %s for %d /%d subnets",
+				tierPath, prefix, l.maxAvailabilityZones, t.spec.PrefixLength,
+			)
+		}
+
+		block, err := cidr.Subnet(prefix, blockBits, int(offset/blockSize))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", tierPath, err)
+		}
+		offset += blockSize
+
+		tierSlot := l.packedTiers
+		l.packedTiers++
+
+		for i, availabilityZone := range l.availabilityZones {
+			subnetPrefix, err := cidr.Subnet(block, l.availabilityZoneBits, i)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", tierPath, err)
+			}
+
+			ipv6NetNum := tierSlot*l.maxAvailabilityZones + i
+
+			subnets = append(subnets, types.Subnets{
+				Name:             fmt.Sprintf("%s-%s-%s", l.networkingName, t.name, availabilityZone),
+				CidrBlock:        subnetPrefix.String(),
+				PublicIpOnLaunch: t.public,
+				Isolated:         t.isolated,
+				Pods:             t.pods,
+				AvailabilityZone: availabilityZone,
+				Ipv6NetNum:       &ipv6NetNum,
+				Tags:             t.spec.Tags,
+			})
+		}
+	}
+
+	return subnets, nil
+}
+
+// layoutTiers returns the declared tiers, the largest subnets first so every
+// block stays aligned when packed one after the other.
+func layoutTiers(subnetLayout *types.SubnetLayout) []tier {
+	var tiers []tier
+
+	if subnetLayout.Private != nil {
+		tiers = append(tiers, tier{name: "private", spec: *subnetLayout.Private})
+	}
+
+	if subnetLayout.Public != nil {
+		tiers = append(tiers, tier{name: "public", public: true, spec: *subnetLayout.Public})
+	}
+
+	if subnetLayout.Isolated != nil {
+		tiers = append(tiers, tier{name: "isolated", isolated: true, spec: *subnetLayout.Isolated})
+	}
+
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].spec.PrefixLength < tiers[j].spec.PrefixLength
+	})
+
+	return tiers
+}
@@ -0,0 +1,143 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"pulumi-eks/internal/types"
+)
+
+func testNetworking(availabilityZones ...string) *types.Networking {
+	return &types.Networking{
+		Name:      "apps",
+		CidrBlock: "10.0.0.0/16",
+		SubnetLayout: &types.SubnetLayout{
+			AvailabilityZones: availabilityZones,
+			Public:            &types.SubnetTier{PrefixLength: 24},
+			Private:           &types.SubnetTier{PrefixLength: 20},
+		},
+	}
+}
+
+func subnetCidrs(t *testing.T, networking *types.Networking) map[string]string {
+	t.Helper()
+
+	if err := Expand(networking); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cidrs := make(map[string]string, len(networking.Subnets))
+	for _, subnet := range networking.Subnets {
+		cidrs[subnet.Name] = subnet.CidrBlock
+	}
+
+	return cidrs
+}
+
+func TestExpandLaysTiersOutDeterministically(t *testing.T) {
+	expected := map[string]string{
+		"apps-private-us-east-1a": "10.0.0.0/20",
+		"apps-private-us-east-1b": "10.0.16.0/20",
+		"apps-private-us-east-1c": "10.0.32.0/20",
+		"apps-public-us-east-1a":  "10.0.64.0/24",
+		"apps-public-us-east-1b":  "10.0.65.0/24",
+		"apps-public-us-east-1c":  "10.0.66.0/24",
+	}
+
+	for i := 0; i < 3; i++ {
+		actual := subnetCidrs(t, testNetworking("us-east-1a", "us-east-1b", "us-east-1c"))
+
+		if len(actual) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+
+		for name, cidrBlock := range expected {
+			if actual[name] != cidrBlock {
+				t.Errorf("%s: expected %s, got %s", name, cidrBlock, actual[name])
+			}
+		}
+	}
+}
+
+func TestExpandAddingAvailabilityZoneKeepsExistingCidrs(t *testing.T) {
+	before := subnetCidrs(t, testNetworking("us-east-1a", "us-east-1b", "us-east-1c"))
+	after := subnetCidrs(t, testNetworking("us-east-1a", "us-east-1b", "us-east-1c", "us-east-1d"))
+
+	for name, cidrBlock := range before {
+		if after[name] != cidrBlock {
+			t.Errorf("%s moved from %s to %s", name, cidrBlock, after[name])
+		}
+	}
+
+	if len(after) != len(before)+2 {
+		t.Errorf("expected one new subnet per tier, got %v", after)
+	}
+}
+
+func TestExpandRefusesMoreAvailabilityZonesThanTheDefault(t *testing.T) {
+	networking := testNetworking("us-east-1a", "us-east-1b", "us-east-1c", "us-east-1d", "us-east-1e")
+
+	err := Expand(networking)
+	if err == nil || !strings.Contains(err.Error(), "maxAvailabilityZones: 4 is less than the 5 availability zones") {
+		t.Fatalf("expected the availability zones to exceed the default maximum, got %v", err)
+	}
+}
+
+func subnetIpv6NetNums(t *testing.T, networking *types.Networking) map[string]int {
+	t.Helper()
+
+	if err := Expand(networking); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	netNums := make(map[string]int, len(networking.Subnets))
+	for _, subnet := range networking.Subnets {
+		if subnet.Ipv6NetNum == nil {
+			t.Fatalf("%s: expected the ipv6 net number to be pinned", subnet.Name)
+		}
+		netNums[subnet.Name] = *subnet.Ipv6NetNum
+	}
+
+	return netNums
+}
+
+func testIpv6Networking(availabilityZones ...string) *types.Networking {
+	networking := testNetworking(availabilityZones...)
+	networking.Ipv6 = true
+	networking.SecondaryCidrBlocks = []string{"100.64.0.0/16"}
+	networking.SubnetLayout.Pods = &types.SubnetTier{PrefixLength: 20}
+
+	return networking
+}
+
+func TestExpandAddingAvailabilityZoneKeepsExistingIpv6NetNums(t *testing.T) {
+	before := subnetIpv6NetNums(t, testIpv6Networking("us-east-1a", "us-east-1b"))
+	after := subnetIpv6NetNums(t, testIpv6Networking("us-east-1a", "us-east-1b", "us-east-1c"))
+
+	expected := map[string]int{
+		"apps-private-us-east-1a": 0,
+		"apps-private-us-east-1b": 1,
+		"apps-public-us-east-1a":  4,
+		"apps-public-us-east-1b":  5,
+		"apps-pods-us-east-1a":    8,
+		"apps-pods-us-east-1b":    9,
+	}
+
+	for name, netNum := range expected {
+		if before[name] != netNum {
+			t.Errorf("%s: expected net number %d, got %d", name, netNum, before[name])
+		}
+
+		if after[name] != before[name] {
+			t.Errorf("%s moved from net number %d to %d", name, before[name], after[name])
+		}
+	}
+
+	used := make(map[int]string, len(after))
+	for name, netNum := range after {
+		if previous, exists := used[netNum]; exists {
+			t.Errorf("%s and %s share net number %d", name, previous, netNum)
+		}
+		used[netNum] = name
+	}
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"pulumi-eks/internal/service/shared"
+	"pulumi-eks/internal/types"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	defaultAddonServiceAccountNamespace = "kube-system"
+	defaultAddonResolveConflicts        = "OVERWRITE"
+)
+
+type Addons struct {
+	ctx     *pulumi.Context
+	cluster types.Cluster
+	addons  []types.Addon
+
+	addonVersions pulumi.StringMap
+}
+
+func NewAddons(ctx *pulumi.Context, cluster types.Cluster, addons []types.Addon) *Addons {
+	return &Addons{
+		ctx:           ctx,
+		cluster:       cluster,
+		addons:        addons,
+		addonVersions: make(pulumi.StringMap, len(addons)),
+	}
+}
+
+// Run installs the add-ons. With no add-ons nothing is created, but the
+// service is not skipped either so the node groups waiting on it in upgrade
+// mode still run.
+func (a *Addons) Run(dependency *types.InterServicesDependencies) error {
+	for _, addon := range a.addons {
+		if err := a.createAddon(addon, dependency); err != nil {
+			return err
+		}
+	}
+
+	// resolved before anything is applied, a default or latest version moving
+	// on the aws side shows up in the preview
+	if len(a.addons) > 0 {
+		a.ctx.Export("addonVersions", a.addonVersions)
+	}
+
+	if a.cluster.Upgrade {
+		addonVersions := make([]interface{}, len(dependency.Addons))
+		for i, addon := range dependency.Addons {
+			addonVersions[i] = addon.AddonVersion
+		}
+
+		reportUpgradePhase(a.ctx, 1, a.cluster.KubernetesVersion, addonVersions...)
+	}
+
+	return nil
+}
+
+func (a *Addons) Produces() []types.Dependency {
+	return []types.Dependency{types.AddonsDependency}
+}
+
+// Consumes waits for the node groups on a fresh cluster, add-ons such as
+// coredns never becoming healthy without nodes. In upgrade mode the nodes
+// already run and the order flips, the add-ons moving before the node groups.
+func (a *Addons) Consumes() []types.Dependency {
+	if a.cluster.Upgrade {
+		return []types.Dependency{
+			types.ClusterDependency,
+			types.OIDCProviderDependency,
+		}
+	}
+
+	return []types.Dependency{
+		types.ClusterDependency,
+		types.NodeGroupsDependency,
+		types.OIDCProviderDependency,
+	}
+}
+
+func (a *Addons) createAddon(addon types.Addon, dependency *types.InterServicesDependencies) error {
+	dependsOn := shared.RetrieveDependsOnList(dependency)
+
+	addonVersion, err := resolveAddonVersion(a.ctx, addon.Name, a.cluster, addon.Version)
+	if err != nil {
+		return err
+	}
+
+	a.addonVersions[addon.Name] = pulumi.String(addonVersion)
+
+	addonArgs := &eks.AddonArgs{
+		AddonName:                pulumi.String(addon.Name),
+		AddonVersion:             pulumi.String(addonVersion),
+		ClusterName:              dependency.ClusterOutput.EKSCluster.Name,
+		ResolveConflictsOnCreate: pulumi.String(addonResolveConflictsOnCreate(addon)),
+		ResolveConflictsOnUpdate: pulumi.String(addonResolveConflicts(addon)),
+	}
+
+	if len(addon.ConfigurationValues) > 0 {
+		configurationValues, err := json.Marshal(addon.ConfigurationValues)
+		if err != nil {
+			return fmt.Errorf("addon %s configurationValues: %w", addon.Name, err)
+		}
+
+		addonArgs.ConfigurationValues = pulumi.String(string(configurationValues))
+	}
+
+	if role := addon.ServiceAccountRole; role != nil {
+		serviceAccountRole, err := a.addonServiceAccountRole(addon, dependency)
+		if err != nil {
+			return err
+		}
+
+		switch role.Mode {
+		case types.ADDON_ROLE_MODE_IRSA:
+			addonArgs.ServiceAccountRoleArn = serviceAccountRole.Arn
+		default:
+			addonArgs.PodIdentityAssociations = eks.AddonPodIdentityAssociationArray{
+				eks.AddonPodIdentityAssociationArgs{
+					RoleArn:        serviceAccountRole.Arn,
+					ServiceAccount: pulumi.String(role.ServiceAccount),
+				},
+			}
+		}
+
+		dependsOn = append(dependsOn, serviceAccountRole)
+	}
+
+	addonOutput, err := eks.NewAddon(a.ctx, addonUniqueName(a.cluster.Name, addon.Name), addonArgs, pulumi.DependsOn(dependsOn))
+	if err != nil {
+		return err
+	}
+
+	dependency.Addons = append(dependency.Addons, addonOutput)
+
+	return nil
+}
+
+// addonServiceAccountRole creates the role of the add-on service account,
+// trusting either the pod identity agent or the cluster oidc provider.
+func (a *Addons) addonServiceAccountRole(addon types.Addon, dependency *types.InterServicesDependencies) (*iam.Role, error) {
+	role := addon.ServiceAccountRole
+
+	var assumeRolePolicy pulumi.StringOutput
+
+	switch role.Mode {
+	case types.ADDON_ROLE_MODE_IRSA:
+		namespace := role.Namespace
+		if namespace == "" {
+			namespace = defaultAddonServiceAccountNamespace
+		}
+
+		oidcProvider := dependency.OIDCProvider
+		assumeRolePolicy = pulumi.All(oidcProvider.Arn, oidcProvider.Url).ApplyT(func(args []interface{}) (string, error) {
+			issuer := strings.TrimPrefix(args[1].(string), "https://")
+
+			policyJSON, err := json.Marshal(map[string]interface{}{
+				"Version": "2012-10-17",
+				"Statement": []map[string]interface{}{
+					{
+						"Effect":    "Allow",
+						"Action":    "sts:AssumeRoleWithWebIdentity",
+						"Principal": map[string]interface{}{"Federated": args[0].(string)},
+						"Condition": map[string]interface{}{
+							"StringEquals": map[string]string{
+								issuer + ":aud": "sts.amazonaws.com",
+								issuer + ":sub": fmt.Sprintf("system:serviceaccount:%s:%s", namespace, role.ServiceAccount),
+							},
+						},
+					},
+				},
+			})
+
+			return string(policyJSON), err
+		}).(pulumi.StringOutput)
+	default:
+		policyJSON, err := json.Marshal(map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{
+				{
+					"Effect":    "Allow",
+					"Action":    []string{"sts:AssumeRole", "sts:TagSession"},
+					"Principal": map[string]interface{}{"Service": "pods.eks.amazonaws.com"},
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		assumeRolePolicy = pulumi.String(string(policyJSON)).ToStringOutput()
+	}
+
+	roleUniqueName := addonUniqueName(a.cluster.Name, addon.Name) + "-role"
+
+	serviceAccountRole, err := iam.NewRole(a.ctx, roleUniqueName, &iam.RoleArgs{
+		Name:             pulumi.String(roleUniqueName),
+		AssumeRolePolicy: assumeRolePolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policyArn := range role.AwsPolicies {
+		attachUniqueName := fmt.Sprintf("%s-%s", roleUniqueName, policyArn[strings.LastIndex(policyArn, "/")+1:])
+
+		_, err := iam.NewRolePolicyAttachment(a.ctx, attachUniqueName, &iam.RolePolicyAttachmentArgs{
+			Role:      serviceAccountRole,
+			PolicyArn: pulumi.String(policyArn),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return serviceAccountRole, nil
+}
+
+// resolveAddonVersion returns version as is when pinned, otherwise asks eks
+// for the default (no version) or most recent (latest) version compatible
+// with the cluster kubernetes version. In upgrade mode a pinned version must be
+// one of them, a version older than the new kubernetes version supports would
+// otherwise stay pinned.
+func resolveAddonVersion(ctx *pulumi.Context, addonName string, cluster types.Cluster, version string) (string, error) {
+	if version != "" && version != types.ADDON_VERSION_LATEST {
+		if cluster.Upgrade {
+			return version, checkAddonVersion(ctx, addonName, cluster.KubernetesVersion, version)
+		}
+
+		return version, nil
+	}
+
+	return lookupAddonVersion(ctx, addonName, cluster.KubernetesVersion, version == types.ADDON_VERSION_LATEST)
+}
+
+// addonVersionInput leaves the installed version alone when none is set, eks
+// picking its default on creation, and moves it to the default of the new
+// kubernetes version in upgrade mode.
+func addonVersionInput(ctx *pulumi.Context, addonName string, cluster types.Cluster, version string) (pulumi.StringPtrInput, error) {
+	if version == "" && !cluster.Upgrade {
+		return nil, nil
+	}
+
+	addonVersion, err := resolveAddonVersion(ctx, addonName, cluster, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return pulumi.String(addonVersion), nil
+}
+
+// checkAddonVersion refuses a pinned version that is neither the default nor
+// the most recent version eks lists for kubernetesVersion.
+func checkAddonVersion(ctx *pulumi.Context, addonName, kubernetesVersion, version string) error {
+	defaultVersion, err := lookupAddonVersion(ctx, addonName, kubernetesVersion, false)
+	if err != nil {
+		return err
+	}
+
+	latestVersion, err := lookupAddonVersion(ctx, addonName, kubernetesVersion, true)
+	if err != nil {
+		return err
+	}
+
+	if version != defaultVersion && version != latestVersion {
+		return fmt.Errorf(
+			"addon %s: the pinned version %s is neither the default (%s) nor the latest (%s) version for kubernetes %s, pin one of them or drop version while upgrading",
+			addonName, version, defaultVersion, latestVersion, kubernetesVersion,
+		)
+	}
+
+	return nil
+}
+
+func lookupAddonVersion(ctx *pulumi.Context, addonName, kubernetesVersion string, mostRecent bool) (string, error) {
+	addonVersion, err := eks.GetAddonVersion(ctx, &eks.GetAddonVersionArgs{
+		AddonName:         addonName,
+		KubernetesVersion: kubernetesVersion,
+		MostRecent:        pulumi.BoolRef(mostRecent),
+	})
+	if err != nil {
+		return "", fmt.Errorf("resolving the %s addon version for kubernetes %s: %w", addonName, kubernetesVersion, err)
+	}
+
+	return addonVersion.Version, nil
+}
+
+func addonResolveConflicts(addon types.Addon) string {
+	if addon.ResolveConflicts == "" {
+		return defaultAddonResolveConflicts
+	}
+
+	return addon.ResolveConflicts
+}
+
+// addonResolveConflictsOnCreate falls back to NONE for PRESERVE, which eks
+// only accepts on updates.
+func addonResolveConflictsOnCreate(addon types.Addon) string {
+	if resolveConflicts := addonResolveConflicts(addon); resolveConflicts != "PRESERVE" {
+		return resolveConflicts
+	}
+
+	return "NONE"
+}
+
+func addonUniqueName(clusterName, addonName string) string {
+	return fmt.Sprintf("%s-addon-%s", clusterName, addonName)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"pulumi-eks/internal/types"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// addonVersionMocks answers the eks addon version lookups, v1.19.2 being the
+// default and v1.19.3 the most recent version of every addon.
+type addonVersionMocks struct {
+	calls int
+}
+
+func (m *addonVersionMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	return args.Name + "-id", args.Inputs, nil
+}
+
+func (m *addonVersionMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	m.calls++
+
+	version := "v1.19.2-eksbuild.1"
+	if mostRecent, found := args.Args["mostRecent"]; found && mostRecent.BoolValue() {
+		version = "v1.19.3-eksbuild.1"
+	}
+
+	outputs := args.Args.Copy()
+	outputs["version"] = resource.NewStringProperty(version)
+
+	return outputs, nil
+}
+
+func TestResolveAddonVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		upgrade       bool
+		version       string
+		expected      string
+		expectedError string
+		expectedCalls int
+	}{
+		{name: "default", version: "", expected: "v1.19.2-eksbuild.1", expectedCalls: 1},
+		{name: "latest", version: types.ADDON_VERSION_LATEST, expected: "v1.19.3-eksbuild.1", expectedCalls: 1},
+		{name: "pinned", version: "v1.18.0-eksbuild.1", expected: "v1.18.0-eksbuild.1"},
+		{name: "upgrade pinned to the default", upgrade: true, version: "v1.19.2-eksbuild.1", expected: "v1.19.2-eksbuild.1", expectedCalls: 2},
+		{name: "upgrade pinned to the latest", upgrade: true, version: "v1.19.3-eksbuild.1", expected: "v1.19.3-eksbuild.1", expectedCalls: 2},
+		{name: "upgrade pinned to an older version", upgrade: true, version: "v1.18.0-eksbuild.1", expectedError: "the pinned version v1.18.0-eksbuild.1 is neither the default (v1.19.2-eksbuild.1) nor the latest (v1.19.3-eksbuild.1) version for kubernetes 1.31", expectedCalls: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mocks := &addonVersionMocks{}
+			cluster := types.Cluster{KubernetesVersion: "1.31", Upgrade: test.upgrade}
+
+			var actual string
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				var err error
+				actual, err = resolveAddonVersion(ctx, "coredns", cluster, test.version)
+				return err
+			}, pulumi.WithMocks("pulumi-eks", "test", mocks))
+
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error %q, got %v", test.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if actual != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+
+			if mocks.calls != test.expectedCalls {
+				t.Errorf("expected %d version lookups, got %d", test.expectedCalls, mocks.calls)
+			}
+		})
+	}
+}
+
+func TestAddonVersionInputKeepsTheInstalledVersion(t *testing.T) {
+	mocks := &addonVersionMocks{}
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		version, err := addonVersionInput(ctx, "eks-pod-identity-agent", types.Cluster{KubernetesVersion: "1.31"}, "")
+		if version != nil {
+			t.Errorf("expected no version outside upgrade mode, got %v", version)
+		}
+		return err
+	}, pulumi.WithMocks("pulumi-eks", "test", mocks))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mocks.calls != 0 {
+		t.Errorf("expected no version lookup, got %d", mocks.calls)
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"pulumi-eks/internal/types"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ssm"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/vpc"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	defaultBastionInstanceType = "t3.micro"
+	bastionAmiParameter        = "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64"
+)
+
+// createBastion provisions an instance managed by SSM in a private subnet,
+// allowed to reach the api on 443. It has no ingress rule and no key pair,
+// the only way in is an SSM session.
+func (c *ClusterEKS) createBastion(dependency *types.InterServicesDependencies) error {
+	bastion := c.cluster.Bastion
+	if bastion == nil {
+		return nil
+	}
+
+	subnetID, err := c.bastionSubnetID(dependency)
+	if err != nil {
+		return err
+	}
+
+	ami, instanceOpts, err := c.bastionAmi()
+	if err != nil {
+		return err
+	}
+
+	instanceProfile, err := c.bastionInstanceProfile()
+	if err != nil {
+		return err
+	}
+
+	bastionUniqueName := fmt.Sprintf("%s-bastion", c.cluster.Name)
+
+	securityGroup, err := ec2.NewSecurityGroup(c.ctx, bastionUniqueName, &ec2.SecurityGroupArgs{
+		Name:        pulumi.String(bastionUniqueName),
+		Description: pulumi.String("SSM managed bastion of the cluster api, no inbound access"),
+		Tags:        pulumi.StringMap{"Name": pulumi.String(bastionUniqueName)},
+		VpcId:       c.clusterOutput.VpcConfig.VpcId().Elem(),
+		Egress: ec2.SecurityGroupEgressArray{
+			ec2.SecurityGroupEgressArgs{
+				Protocol:   pulumi.String("-1"),
+				FromPort:   pulumi.Int(0),
+				ToPort:     pulumi.Int(0),
+				CidrBlocks: pulumi.StringArray{pulumi.String(types.PUBLIC_CIDR)},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = vpc.NewSecurityGroupIngressRule(c.ctx, bastionUniqueName+"-api", &vpc.SecurityGroupIngressRuleArgs{
+		ReferencedSecurityGroupId: securityGroup.ID(),
+		FromPort:                  pulumi.Int(443),
+		ToPort:                    pulumi.Int(443),
+		IpProtocol:                pulumi.String("tcp"),
+		SecurityGroupId:           c.clusterOutput.VpcConfig.ClusterSecurityGroupId().Elem(),
+	})
+	if err != nil {
+		return err
+	}
+
+	instanceType := bastion.InstanceType
+	if instanceType == "" {
+		instanceType = defaultBastionInstanceType
+	}
+
+	instance, err := ec2.NewInstance(c.ctx, bastionUniqueName, &ec2.InstanceArgs{
+		Ami:                 pulumi.String(ami),
+		InstanceType:        pulumi.String(instanceType),
+		SubnetId:            subnetID,
+		IamInstanceProfile:  instanceProfile.Name,
+		VpcSecurityGroupIds: pulumi.StringArray{securityGroup.ID()},
+		Tags:                pulumi.StringMap{"Name": pulumi.String(bastionUniqueName)},
+		MetadataOptions: &ec2.InstanceMetadataOptionsArgs{
+			HttpTokens: pulumi.String("required"),
+		},
+	}, instanceOpts...)
+	if err != nil {
+		return err
+	}
+
+	c.ctx.Export("bastionInstanceId", instance.ID())
+	c.ctx.Export("bastionTunnelCommand", bastionTunnelCommand(instance.ID().ToStringOutput(), c.clusterOutput.Endpoint, bastion.TunnelPort))
+
+	return nil
+}
+
+// bastionAmi returns the pinned bastion.ami, or the latest al2023 ami. The
+// latest ami is only read on creation, a new al2023 release would otherwise
+// replace the bastion and drop the open tunnels on an unrelated update.
+func (c *ClusterEKS) bastionAmi() (string, []pulumi.ResourceOption, error) {
+	if c.cluster.Bastion.Ami != "" {
+		return c.cluster.Bastion.Ami, nil, nil
+	}
+
+	ami, err := ssm.LookupParameter(c.ctx, &ssm.LookupParameterArgs{Name: bastionAmiParameter})
+	if err != nil {
+		return "", nil, fmt.Errorf("looking up the bastion ami: %w", err)
+	}
+
+	return ami.Value, []pulumi.ResourceOption{pulumi.IgnoreChanges([]string{"ami"})}, nil
+}
+
+// bastionSubnetID resolves bastion.subnet like the cluster subnets, a subnet
+// name or a raw subnet id, defaulting to the first private subnet.
+func (c *ClusterEKS) bastionSubnetID(dependency *types.InterServicesDependencies) (pulumi.StringInput, error) {
+	subnet := c.cluster.Bastion.Subnet
+
+	if subnet == "" {
+		privateSubnetList := dependency.Subnets[types.PRIVATE_SUBNET]
+		if len(privateSubnetList) == 0 {
+			return nil, fmt.Errorf("the bastion requires a private subnet, none was found in the subnets map")
+		}
+
+		return privateSubnetList[0].ID(), nil
+	}
+
+	// a subnet name wins over an id, even when it starts with subnet-
+	if subnetOutput, found := dependency.SubnetsByName[subnet]; found {
+		return subnetOutput.ID(), nil
+	}
+
+	if !strings.HasPrefix(subnet, types.SUBNET_ID_PREFIX) {
+		return nil, fmt.Errorf("bastion subnet %q was not found in the networking subnets", subnet)
+	}
+
+	return pulumi.String(subnet), nil
+}
+
+func (c *ClusterEKS) bastionInstanceProfile() (*iam.InstanceProfile, error) {
+	bastionPolicyJSON, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Action": "sts:AssumeRole",
+				"Effect": "Allow",
+				"Principal": map[string]interface{}{
+					"Service": "ec2.amazonaws.com",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bastionRoleName := fmt.Sprintf("%s-bastionrole", c.cluster.Name)
+	bastionRole, err := iam.NewRole(c.ctx, bastionRoleName, &iam.RoleArgs{
+		Name:             pulumi.String(bastionRoleName),
+		AssumeRolePolicy: pulumi.String(string(bastionPolicyJSON)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = iam.NewRolePolicyAttachment(c.ctx, bastionRoleName+"-attachment", &iam.RolePolicyAttachmentArgs{
+		Role:      bastionRole,
+		PolicyArn: pulumi.String("arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return iam.NewInstanceProfile(c.ctx, bastionRoleName, &iam.InstanceProfileArgs{
+		Name: pulumi.String(bastionRoleName),
+		Role: bastionRole.Name,
+	})
+}
+
+func bastionTunnelCommand(instanceID, clusterEndpoint pulumi.StringOutput, tunnelPort int) pulumi.StringOutput {
+	if tunnelPort == 0 {
+		tunnelPort = 443
+	}
+
+	return pulumi.Sprintf(
+		"aws ssm start-session --target %s --document-name AWS-StartPortForwardingSessionToRemoteHost --parameters host=%s,portNumber=443,localPortNumber=%d",
+		instanceID, clusterEndpointHost(clusterEndpoint), tunnelPort,
+	)
+}
+
+// clusterEndpointHost strips the scheme off the cluster endpoint.
+func clusterEndpointHost(clusterEndpoint pulumi.StringOutput) pulumi.StringOutput {
+	return clusterEndpoint.ApplyT(func(endpoint string) string {
+		return strings.TrimPrefix(endpoint, "https://")
+	}).(pulumi.StringOutput)
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"pulumi-eks/internal/types"
 	"pulumi-eks/pkg/generic"
+	"strings"
 
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
@@ -28,6 +30,9 @@ type ClusterEKS struct {
 type clusterDependsOn struct {
 	clusterRoleAttachment *iam.RolePolicyAttachment
 	clusterRole           *iam.Role
+	encryptionKeyArn      pulumi.StringOutput
+	encryptionPolicy      *iam.RolePolicy
+	logGroup              *cloudwatch.LogGroup
 }
 
 func NewClusterEKS(ctx *pulumi.Context, networking types.Networking, cluster types.Cluster, nodes []types.NodeGroups) *ClusterEKS {
@@ -41,9 +46,14 @@ func NewClusterEKS(ctx *pulumi.Context, networking types.Networking, cluster typ
 
 func (c *ClusterEKS) Run(dependency *types.InterServicesDependencies) error {
 	steps := []func() error{
+		func() error { return c.checkUpgrade() },
 		func() error { return c.createEKSRole() },
+		func() error { return c.createEncryptionKey() },
+		func() error { return c.createClusterLogGroup() },
 		func() error { return c.createEKSCluster(dependency) },
 		func() error { return c.modifyEKSSecurityGroup() },
+		func() error { return c.createBastion(dependency) },
+		func() error { return c.createAccessEntries() },
 	}
 
 	for _, step := range steps {
@@ -55,36 +65,56 @@ func (c *ClusterEKS) Run(dependency *types.InterServicesDependencies) error {
 	return nil
 }
 
+func (c *ClusterEKS) Produces() []types.Dependency {
+	return []types.Dependency{types.ClusterDependency}
+}
+
+func (c *ClusterEKS) Consumes() []types.Dependency {
+	return []types.Dependency{types.SubnetsDependency}
+}
+
 func (c *ClusterEKS) createEKSCluster(dependency *types.InterServicesDependencies) error {
-	publicSubnetList, found := dependency.Subnets[types.PUBLIC_SUBNET]
-	if !found {
-		return fmt.Errorf("public subnets were not found in the subnets map")
+	subnetIds, err := c.clusterSubnetIds(dependency)
+	if err != nil {
+		return err
 	}
 
-	pulumiIDOutputList := generic.ToStringOutputList(
-		publicSubnetList, func(subnet *ec2.Subnet) pulumi.StringOutput {
-			return subnet.ID().ToStringOutput()
-		})
+	dependsOn := []pulumi.Resource{c.dependencies.clusterRoleAttachment}
+	if c.dependencies.encryptionPolicy != nil {
+		dependsOn = append(dependsOn, c.dependencies.encryptionPolicy)
+	}
+
+	// the log group must exist before eks creates it on its own, unmanaged
+	if c.dependencies.logGroup != nil {
+		dependsOn = append(dependsOn, c.dependencies.logGroup)
+	}
 
 	clusterOutput, err := eks.NewCluster(c.ctx, c.cluster.Name, &eks.ClusterArgs{
-		Name:    pulumi.String(c.cluster.Name),
-		Version: pulumi.String(c.cluster.KubernetesVersion),
-		RoleArn: c.dependencies.clusterRole.Arn,
+		Name:                   pulumi.String(c.cluster.Name),
+		Version:                pulumi.String(c.cluster.KubernetesVersion),
+		RoleArn:                c.dependencies.clusterRole.Arn,
+		AccessConfig:           clusterAccessConfig(c.cluster),
+		EncryptionConfig:       c.clusterEncryptionConfig(),
+		EnabledClusterLogTypes: clusterLogTypes(c.cluster),
+		KubernetesNetworkConfig: &eks.ClusterKubernetesNetworkConfigArgs{
+			IpFamily: pulumi.String(clusterIpFamily(c.cluster)),
+		},
 		VpcConfig: &eks.ClusterVpcConfigArgs{
-			SubnetIds:             pulumi.ToStringArrayOutput(pulumiIDOutputList),
+			SubnetIds:             subnetIds,
+			SecurityGroupIds:      pulumi.ToStringArray(c.cluster.SecurityGroups),
 			EndpointPrivateAccess: pulumi.BoolPtr(true),
-			EndpointPublicAccess:  pulumi.BoolPtr(true),
+			EndpointPublicAccess:  pulumi.BoolPtr(c.cluster.EndpointAccess != types.ENDPOINT_ACCESS_PRIVATE),
+			PublicAccessCidrs:     publicAccessCidrs(c.cluster.ApiAccess),
 		},
-	}, pulumi.DependsOn([]pulumi.Resource{
-		c.dependencies.clusterRoleAttachment,
-	}))
+	}, pulumi.DependsOn(dependsOn))
 
 	if err != nil {
 		return err
 	}
 
 	kubeConfig := generateKubeconfig(
-		clusterOutput.Endpoint,
+		c.kubernetesApiServer(clusterOutput.Endpoint),
+		c.kubernetesTlsServerName(clusterOutput.Endpoint),
 		clusterOutput.CertificateAuthority.Data().Elem(),
 		clusterOutput.Name,
 		c.cluster.Region,
@@ -99,20 +129,93 @@ func (c *ClusterEKS) createEKSCluster(dependency *types.InterServicesDependencie
 
 	dependency.ClusterOutput = clusterOutputDTO
 
+	if c.cluster.Upgrade {
+		reportUpgradePhase(c.ctx, 0, c.cluster.KubernetesVersion, clusterOutput.Version)
+	}
+
 	return nil
 }
 
+// clusterSubnetIds resolves cluster.subnets, each entry being either the name
+// of a subnet from the networking spec or a raw subnet ID, a name winning over
+// an id even when it starts with subnet-. When no subnets are listed, every
+// public subnet is used.
+func (c *ClusterEKS) clusterSubnetIds(dependency *types.InterServicesDependencies) (pulumi.StringArrayOutput, error) {
+	if len(c.cluster.Subnets) == 0 {
+		publicSubnetList, found := dependency.Subnets[types.PUBLIC_SUBNET]
+		if !found {
+			return pulumi.StringArrayOutput{}, fmt.Errorf("public subnets were not found in the subnets map")
+		}
+
+		pulumiIDOutputList := generic.ToStringOutputList(
+			publicSubnetList, func(subnet *ec2.Subnet) pulumi.StringOutput {
+				return subnet.ID().ToStringOutput()
+			})
+
+		return pulumi.ToStringArrayOutput(pulumiIDOutputList), nil
+	}
+
+	pulumiIDOutputList := make([]pulumi.StringOutput, len(c.cluster.Subnets))
+
+	for i, subnet := range c.cluster.Subnets {
+		if subnetOutput, found := dependency.SubnetsByName[subnet]; found {
+			pulumiIDOutputList[i] = subnetOutput.ID().ToStringOutput()
+			continue
+		}
+
+		if !strings.HasPrefix(subnet, types.SUBNET_ID_PREFIX) {
+			return pulumi.StringArrayOutput{}, fmt.Errorf("cluster subnet %q was not found in the networking subnets", subnet)
+		}
+
+		pulumiIDOutputList[i] = pulumi.String(subnet).ToStringOutput()
+	}
+
+	return pulumi.ToStringArrayOutput(pulumiIDOutputList), nil
+}
+
+// modifyEKSSecurityGroup opens 443 on the cluster security group to the
+// allowed cidrs and security groups only, nothing being opened when both are
+// empty.
 func (c *ClusterEKS) modifyEKSSecurityGroup() error {
-	modifyUniqueName := fmt.Sprintf("%s-modifySG", c.cluster.Name)
-	_, err := vpc.NewSecurityGroupIngressRule(c.ctx, modifyUniqueName, &vpc.SecurityGroupIngressRuleArgs{
-		CidrIpv4:        pulumi.String(types.PUBLIC_CIDR),
-		FromPort:        pulumi.Int(443),
-		ToPort:          pulumi.Int(443),
-		IpProtocol:      pulumi.String("tcp"),
-		SecurityGroupId: c.clusterOutput.VpcConfig.ClusterSecurityGroupId().Elem(),
-	})
+	apiAccess := c.cluster.ApiAccess
+	clusterSecurityGroupID := c.clusterOutput.VpcConfig.ClusterSecurityGroupId().Elem()
 
-	return err
+	for _, cidr := range apiAccess.AllowedCidrs {
+		ruleArgs := &vpc.SecurityGroupIngressRuleArgs{
+			FromPort:        pulumi.Int(443),
+			ToPort:          pulumi.Int(443),
+			IpProtocol:      pulumi.String("tcp"),
+			SecurityGroupId: clusterSecurityGroupID,
+		}
+
+		if strings.Contains(cidr, ":") {
+			ruleArgs.CidrIpv6 = pulumi.String(cidr)
+		} else {
+			ruleArgs.CidrIpv4 = pulumi.String(cidr)
+		}
+
+		ruleUniqueName := fmt.Sprintf("%s-api-%s", c.cluster.Name, strings.NewReplacer(".", "-", ":", "-", "/", "-").Replace(cidr))
+		if _, err := vpc.NewSecurityGroupIngressRule(c.ctx, ruleUniqueName, ruleArgs); err != nil {
+			return err
+		}
+	}
+
+	for _, securityGroup := range apiAccess.AllowedSecurityGroups {
+		ruleUniqueName := fmt.Sprintf("%s-api-%s", c.cluster.Name, securityGroup)
+
+		_, err := vpc.NewSecurityGroupIngressRule(c.ctx, ruleUniqueName, &vpc.SecurityGroupIngressRuleArgs{
+			ReferencedSecurityGroupId: pulumi.String(securityGroup),
+			FromPort:                  pulumi.Int(443),
+			ToPort:                    pulumi.Int(443),
+			IpProtocol:                pulumi.String("tcp"),
+			SecurityGroupId:           clusterSecurityGroupID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (c *ClusterEKS) createEKSRole() error {
@@ -159,12 +262,59 @@ func (c *ClusterEKS) createEKSRole() error {
 	return err
 }
 
-func generateKubeconfig(clusterEndpoint pulumi.StringOutput, certData pulumi.StringOutput, clusterName pulumi.StringOutput, clusterRegion string) pulumi.StringOutput {
+func clusterIpFamily(cluster types.Cluster) string {
+	if cluster.IpFamily == "" {
+		return types.IP_FAMILY_IPV4
+	}
+
+	return cluster.IpFamily
+}
+
+// publicAccessCidrs leaves the aws default (0.0.0.0/0) when no cidr is listed.
+func publicAccessCidrs(apiAccess types.ApiAccess) pulumi.StringArrayInput {
+	if len(apiAccess.PublicAccessCidrs) == 0 {
+		return nil
+	}
+
+	return pulumi.ToStringArray(apiAccess.PublicAccessCidrs)
+}
+
+// kubernetesApiServer is the server the kubernetes providers talk to, the
+// local end of the bastion tunnel when tunnelPort is set.
+func (c *ClusterEKS) kubernetesApiServer(clusterEndpoint pulumi.StringOutput) pulumi.StringOutput {
+	if c.cluster.Bastion == nil || c.cluster.Bastion.TunnelPort == 0 {
+		return clusterEndpoint
+	}
+
+	return pulumi.Sprintf("https://localhost:%d", c.cluster.Bastion.TunnelPort)
+}
+
+// kubernetesTlsServerName is only set behind the bastion tunnel, the
+// certificate being issued for the cluster endpoint and not localhost. Stacks
+// without the tunnel keep their kubeconfig, a changed kubeconfig making
+// pulumi-kubernetes replace its provider and the resources behind it.
+func (c *ClusterEKS) kubernetesTlsServerName(clusterEndpoint pulumi.StringOutput) pulumi.StringOutput {
+	if c.cluster.Bastion == nil || c.cluster.Bastion.TunnelPort == 0 {
+		return pulumi.String("").ToStringOutput()
+	}
+
+	return clusterEndpointHost(clusterEndpoint)
+}
+
+func generateKubeconfig(clusterEndpoint pulumi.StringOutput, tlsServerName pulumi.StringOutput, certData pulumi.StringOutput, clusterName pulumi.StringOutput, clusterRegion string) pulumi.StringOutput {
+	tlsServerNameField := tlsServerName.ApplyT(func(name string) string {
+		if name == "" {
+			return ""
+		}
+
+		return fmt.Sprintf("\n                \"tls-server-name\": \"%s\",", name)
+	}).(pulumi.StringOutput)
+
 	return pulumi.Sprintf(`{
         "apiVersion": "v1",
         "clusters": [{
             "cluster": {
-                "server": "%s",
+                "server": "%s",%s
                 "certificate-authority-data": "%s"
             },
             "name": "kubernetes",
@@ -197,5 +347,5 @@ func generateKubeconfig(clusterEndpoint pulumi.StringOutput, certData pulumi.Str
                 }
             }
         }]
-    }`, clusterEndpoint, certData, clusterName, clusterRegion)
+    }`, clusterEndpoint, tlsServerNameField, certData, clusterName, clusterRegion)
 }
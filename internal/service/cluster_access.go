@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const accessPolicyArnPrefix = "arn:aws:eks::aws:cluster-access-policy/"
+
+// clusterAccessConfig leaves the cluster untouched when no access section is
+// set, access entries being only available with the API modes.
+func clusterAccessConfig(cluster types.Cluster) eks.ClusterAccessConfigPtrInput {
+	if cluster.Access == nil {
+		return nil
+	}
+
+	return &eks.ClusterAccessConfigArgs{
+		AuthenticationMode:                      pulumi.String(clusterAuthenticationMode(cluster.Access)),
+		BootstrapClusterCreatorAdminPermissions: pulumi.Bool(true),
+	}
+}
+
+func clusterAuthenticationMode(access *types.ClusterAccess) string {
+	if access.AuthenticationMode == "" {
+		return types.AUTHENTICATION_MODE_API_AND_CONFIG_MAP
+	}
+
+	return access.AuthenticationMode
+}
+
+// createAccessEntries creates an access entry per principal and associates
+// its access policies, cluster wide or to the namespaces listed.
+func (c *ClusterEKS) createAccessEntries() error {
+	if c.cluster.Access == nil {
+		return nil
+	}
+
+	// entries used to be named after the role or user alone, the ones whose
+	// name is still unique keep their previous name as an alias instead of
+	// being replaced
+	resourceNames := make(map[string]int, len(c.cluster.Access.Entries))
+	for _, entry := range c.cluster.Access.Entries {
+		resourceNames[principalResourceName(entry.PrincipalArn)]++
+	}
+
+	for _, entry := range c.cluster.Access.Entries {
+		entryUniqueName := fmt.Sprintf("%s-access-%s", c.cluster.Name, principalUniqueName(entry.PrincipalArn))
+		previousUniqueName := fmt.Sprintf("%s-access-%s", c.cluster.Name, principalResourceName(entry.PrincipalArn))
+		previousNameUnique := resourceNames[principalResourceName(entry.PrincipalArn)] == 1
+
+		accessEntry, err := eks.NewAccessEntry(c.ctx, entryUniqueName, &eks.AccessEntryArgs{
+			ClusterName:      c.clusterOutput.Name,
+			PrincipalArn:     pulumi.String(entry.PrincipalArn),
+			KubernetesGroups: pulumi.ToStringArray(entry.KubernetesGroups),
+		}, previousNameAlias(previousNameUnique, previousUniqueName))
+		if err != nil {
+			return err
+		}
+
+		for _, policy := range entry.Policies {
+			policyArn := accessPolicyArn(policy.Policy)
+
+			accessScope := eks.AccessPolicyAssociationAccessScopeArgs{
+				Type: pulumi.String("cluster"),
+			}
+
+			if len(policy.Namespaces) > 0 {
+				accessScope.Type = pulumi.String("namespace")
+				accessScope.Namespaces = pulumi.ToStringArray(policy.Namespaces)
+			}
+
+			policyName := policyArn[strings.LastIndex(policyArn, "/")+1:]
+			associationUniqueName := fmt.Sprintf("%s-%s", entryUniqueName, policyName)
+
+			_, err := eks.NewAccessPolicyAssociation(c.ctx, associationUniqueName, &eks.AccessPolicyAssociationArgs{
+				ClusterName:  c.clusterOutput.Name,
+				PrincipalArn: accessEntry.PrincipalArn,
+				PolicyArn:    pulumi.String(policyArn),
+				AccessScope:  accessScope,
+			}, previousNameAlias(previousNameUnique, fmt.Sprintf("%s-%s", previousUniqueName, policyName)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// previousNameAlias aliases the resource to its previous name, two resources
+// cannot share the same alias.
+func previousNameAlias(unique bool, previousName string) pulumi.ResourceOption {
+	if !unique {
+		return pulumi.Aliases(nil)
+	}
+
+	return pulumi.Aliases([]pulumi.Alias{{Name: pulumi.String(previousName)}})
+}
+
+// accessPolicyArn expands a policy name such as AmazonEKSViewPolicy to its arn.
+func accessPolicyArn(policy string) string {
+	if strings.HasPrefix(policy, "arn:") {
+		return policy
+	}
+
+	return accessPolicyArnPrefix + policy
+}
+
+// principalUniqueName turns arn:aws:iam::123456789012:role/team/sre into
+// 123456789012-role-team-sre, the same role name in two accounts getting two
+// entries.
+func principalUniqueName(principalArn string) string {
+	parts := strings.Split(principalArn, ":")
+	if len(parts) < 6 || parts[4] == "" {
+		return principalResourceName(principalArn)
+	}
+
+	return parts[4] + "-" + principalResourceName(principalArn)
+}
+
+// principalResourceName turns arn:aws:iam::123456789012:role/team/sre into
+// role-team-sre.
+func principalResourceName(principalArn string) string {
+	resource := principalArn[strings.LastIndex(principalArn, ":")+1:]
+
+	return strings.ReplaceAll(resource, "/", "-")
+}
@@ -0,0 +1,21 @@
+package service
+
+import "testing"
+
+func TestPrincipalUniqueName(t *testing.T) {
+	tests := []struct {
+		principalArn string
+		expected     string
+	}{
+		{"arn:aws:iam::123456789012:role/sre", "123456789012-role-sre"},
+		{"arn:aws:iam::210987654321:role/sre", "210987654321-role-sre"},
+		{"arn:aws:iam::123456789012:role/team/sre", "123456789012-role-team-sre"},
+		{"arn:aws-cn:iam::123456789012:user/ops", "123456789012-user-ops"},
+	}
+
+	for _, test := range tests {
+		if actual := principalUniqueName(test.principalArn); actual != test.expected {
+			t.Errorf("principalUniqueName(%q): expected %q, got %q", test.principalArn, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const defaultKmsKeyDeletionWindowInDays = 30
+
+// clusterKmsActions are the permissions the cluster role needs on the key to
+// envelope encrypt the secrets.
+var clusterKmsActions = []string{"kms:Encrypt", "kms:Decrypt", "kms:ListGrants", "kms:DescribeKey"}
+
+// createEncryptionKey creates the secrets encryption key when no existing key
+// is referenced, and grants the cluster role the use of the key either way.
+func (c *ClusterEKS) createEncryptionKey() error {
+	encryption := c.cluster.Encryption
+	if encryption == nil {
+		return nil
+	}
+
+	keyArn := pulumi.String(encryption.KmsKeyArn).ToStringOutput()
+
+	if encryption.KmsKeyArn == "" {
+		key, err := c.clusterKmsKey()
+		if err != nil {
+			return err
+		}
+
+		keyArn = key.Arn
+	}
+
+	kmsPolicyName := fmt.Sprintf("%s-clusterrole-kms", c.cluster.Name)
+	kmsPolicy, err := iam.NewRolePolicy(c.ctx, kmsPolicyName, &iam.RolePolicyArgs{
+		Name: pulumi.String(kmsPolicyName),
+		Role: c.dependencies.clusterRole.ID(),
+		Policy: keyArn.ApplyT(func(arn string) (string, error) {
+			policyJSON, err := json.Marshal(map[string]interface{}{
+				"Version": "2012-10-17",
+				"Statement": []map[string]interface{}{
+					{
+						"Effect":   "Allow",
+						"Action":   clusterKmsActions,
+						"Resource": arn,
+					},
+				},
+			})
+
+			return string(policyJSON), err
+		}).(pulumi.StringOutput),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.dependencies.encryptionKeyArn = keyArn
+	c.dependencies.encryptionPolicy = kmsPolicy
+
+	return nil
+}
+
+// clusterKmsKey creates a customer managed key with rotation on. The account
+// keeps full control of the key, the cluster role may only use it.
+func (c *ClusterEKS) clusterKmsKey() (*kms.Key, error) {
+	callerIdentity, err := aws.GetCallerIdentity(c.ctx, &aws.GetCallerIdentityArgs{})
+	if err != nil {
+		return nil, err
+	}
+
+	partition, err := aws.GetPartition(c.ctx, &aws.GetPartitionArgs{})
+	if err != nil {
+		return nil, err
+	}
+
+	keyPolicy := c.dependencies.clusterRole.Arn.ApplyT(func(clusterRoleArn string) (string, error) {
+		policyJSON, err := json.Marshal(map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{
+				{
+					"Sid":       "AccountAdministration",
+					"Effect":    "Allow",
+					"Principal": map[string]interface{}{"AWS": fmt.Sprintf("arn:%s:iam::%s:root", partition.Partition, callerIdentity.AccountId)},
+					"Action":    "kms:*",
+					"Resource":  "*",
+				},
+				{
+					"Sid":       "ClusterSecretsEncryption",
+					"Effect":    "Allow",
+					"Principal": map[string]interface{}{"AWS": clusterRoleArn},
+					"Action":    clusterKmsActions,
+					"Resource":  "*",
+				},
+				{
+					// lets the key encrypt the control plane log group too
+					"Sid":       "ClusterLogGroupEncryption",
+					"Effect":    "Allow",
+					"Principal": map[string]interface{}{"Service": fmt.Sprintf("logs.%s.%s", c.cluster.Region, partition.DnsSuffix)},
+					"Action":    []string{"kms:Encrypt*", "kms:Decrypt*", "kms:ReEncrypt*", "kms:GenerateDataKey*", "kms:Describe*"},
+					"Resource":  "*",
+					"Condition": map[string]interface{}{
+						"ArnEquals": map[string]string{
+							"kms:EncryptionContext:aws:logs:arn": fmt.Sprintf(
+								"arn:%s:logs:%s:%s:log-group:%s",
+								partition.Partition, c.cluster.Region, callerIdentity.AccountId, clusterLogGroupName(c.cluster.Name),
+							),
+						},
+					},
+				},
+			},
+		})
+
+		return string(policyJSON), err
+	}).(pulumi.StringOutput)
+
+	deletionWindowInDays := c.cluster.Encryption.DeletionWindowInDays
+	if deletionWindowInDays == 0 {
+		deletionWindowInDays = defaultKmsKeyDeletionWindowInDays
+	}
+
+	keyUniqueName := fmt.Sprintf("%s-secrets", c.cluster.Name)
+
+	key, err := kms.NewKey(c.ctx, keyUniqueName, &kms.KeyArgs{
+		Description:          pulumi.Sprintf("envelope encryption of the %s cluster secrets", c.cluster.Name),
+		EnableKeyRotation:    pulumi.Bool(true),
+		DeletionWindowInDays: pulumi.Int(deletionWindowInDays),
+		Policy:               keyPolicy,
+		Tags:                 pulumi.StringMap{"Name": pulumi.String(keyUniqueName)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = kms.NewAlias(c.ctx, keyUniqueName, &kms.AliasArgs{
+		Name:        pulumi.Sprintf("alias/%s", keyUniqueName),
+		TargetKeyId: key.KeyId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (c *ClusterEKS) clusterEncryptionConfig() eks.ClusterEncryptionConfigPtrInput {
+	if c.cluster.Encryption == nil {
+		return nil
+	}
+
+	return &eks.ClusterEncryptionConfigArgs{
+		Provider: &eks.ClusterEncryptionConfigProviderArgs{
+			KeyArn: c.dependencies.encryptionKeyArn,
+		},
+		Resources: pulumi.StringArray{pulumi.String("secrets")},
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// createClusterLogGroup pre-creates the log group eks writes the control
+// plane logs to. Left to eks, it would be created outside of the stack and
+// orphaned on destroy.
+func (c *ClusterEKS) createClusterLogGroup() error {
+	logging := c.cluster.Logging
+	if logging == nil || len(logging.Types) == 0 {
+		return nil
+	}
+
+	logGroupArgs := &cloudwatch.LogGroupArgs{
+		Name:            pulumi.String(clusterLogGroupName(c.cluster.Name)),
+		RetentionInDays: pulumi.Int(logging.RetentionInDays),
+		Tags:            pulumi.StringMap{"Name": pulumi.String(clusterLogGroupName(c.cluster.Name))},
+	}
+
+	if logging.KmsKeyArn != "" {
+		logGroupArgs.KmsKeyId = pulumi.String(logging.KmsKeyArn)
+	}
+
+	logGroup, err := cloudwatch.NewLogGroup(c.ctx, fmt.Sprintf("%s-cluster-logs", c.cluster.Name), logGroupArgs)
+	if err != nil {
+		return err
+	}
+
+	c.dependencies.logGroup = logGroup
+
+	return nil
+}
+
+func clusterLogGroupName(clusterName string) string {
+	return fmt.Sprintf("/aws/eks/%s/cluster", clusterName)
+}
+
+func clusterLogTypes(cluster types.Cluster) pulumi.StringArrayInput {
+	if cluster.Logging == nil {
+		return nil
+	}
+
+	return pulumi.ToStringArray(cluster.Logging.Types)
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"pulumi-eks/internal/types"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestClusterSubnetIdsPrefersSubnetNames(t *testing.T) {
+	ids := make(chan []string, 1)
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		subnet, err := ec2.NewSubnet(ctx, "subnet-apps-1a", &ec2.SubnetArgs{VpcId: pulumi.String("vpc-1")})
+		if err != nil {
+			return err
+		}
+
+		c := &ClusterEKS{
+			ctx:     ctx,
+			cluster: types.Cluster{Subnets: []string{"subnet-apps-1a", "subnet-0123456789abcdef0"}},
+		}
+
+		subnetIds, err := c.clusterSubnetIds(&types.InterServicesDependencies{
+			SubnetsByName: map[string]*ec2.Subnet{"subnet-apps-1a": subnet},
+		})
+		if err != nil {
+			return err
+		}
+
+		subnetIds.ApplyT(func(subnetIds []string) error {
+			ids <- subnetIds
+			return nil
+		})
+
+		return nil
+	}, pulumi.WithMocks("pulumi-eks", "test", newNetworkingMocks()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"subnet-apps-1a-id", "subnet-0123456789abcdef0"}
+	if actual := <-ids; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
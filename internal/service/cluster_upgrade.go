@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// upgradePhases are reported in this order, pulumi only updating a resource
+// once everything it depends on is done: the control plane, the add-ons then
+// the node groups rolled onto the ami of the new version.
+var upgradePhases = []string{"control plane", "add-ons", "node groups"}
+
+var kubernetesVersionPattern = regexp.MustCompile(`^([0-9]+)\.([0-9]+)$`)
+
+const upgradeVersionPath = "spec.cluster.kubernetesVersion"
+
+// checkUpgrade compares kubernetesVersion with the version the cluster runs
+// and refuses downgrades and skipped minor versions, eks only moving the
+// control plane one minor version at a time.
+func (c *ClusterEKS) checkUpgrade() error {
+	if !c.cluster.Upgrade {
+		return nil
+	}
+
+	running, err := eks.LookupCluster(c.ctx, &eks.LookupClusterArgs{Name: c.cluster.Name})
+	if err != nil {
+		return fmt.Errorf("upgrade mode requires the %s cluster to be running: %w", c.cluster.Name, err)
+	}
+
+	if err := checkUpgradeStep(running.Version, c.cluster.KubernetesVersion); err != nil {
+		return err
+	}
+
+	c.ctx.Export("upgrade", pulumi.Sprintf("%s -> %s", running.Version, c.cluster.KubernetesVersion))
+
+	if c.ctx.DryRun() {
+		return c.ctx.Log.Info(fmt.Sprintf("upgrade plan %s -> %s: %s", running.Version, c.cluster.KubernetesVersion, strings.Join(upgradePhases, ", then ")), nil)
+	}
+
+	return nil
+}
+
+// checkUpgradeStep accepts the running version itself, to resume an upgrade
+// stopped halfway, or the next minor version.
+func checkUpgradeStep(running, target string) error {
+	runningMajor, runningMinor, err := parseKubernetesVersion("running cluster version", running)
+	if err != nil {
+		return err
+	}
+
+	targetMajor, targetMinor, err := parseKubernetesVersion(upgradeVersionPath, target)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case targetMajor != runningMajor:
+		return fmt.Errorf("%s: %s changes the major version of the running %s, eks only upgrades minor versions", upgradeVersionPath, target, running)
+	case targetMinor < runningMinor:
+		return fmt.Errorf("%s: %s is older than the running %s, eks does not downgrade clusters", upgradeVersionPath, target, running)
+	case targetMinor > runningMinor+1:
+		return fmt.Errorf("%s: %s skips minor versions from the running %s, upgrade to %d.%d first", upgradeVersionPath, target, running, runningMajor, runningMinor+1)
+	}
+
+	return nil
+}
+
+// parseKubernetesVersion splits a major.minor version, eks versions carrying
+// no patch number.
+func parseKubernetesVersion(path, version string) (int, int, error) {
+	matches := kubernetesVersionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return 0, 0, fmt.Errorf("%s: %q is not a major.minor kubernetes version", path, version)
+	}
+
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+
+	return major, minor, nil
+}
+
+// reportUpgradePhase logs the phase once every output given resolved, that is
+// once pulumi applied the resources behind them.
+func reportUpgradePhase(ctx *pulumi.Context, phase int, kubernetesVersion string, outputs ...interface{}) {
+	if ctx.DryRun() {
+		return
+	}
+
+	pulumi.All(outputs...).ApplyT(func([]interface{}) error {
+		return ctx.Log.Info(fmt.Sprintf("upgrade phase %d/%d: %s on kubernetes %s", phase+1, len(upgradePhases), upgradePhases[phase], kubernetesVersion), nil)
+	})
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckUpgradeStep(t *testing.T) {
+	tests := []struct {
+		name          string
+		running       string
+		target        string
+		expectedError string
+	}{
+		{name: "same version", running: "1.30", target: "1.30"},
+		{name: "next version", running: "1.30", target: "1.31"},
+		{name: "downgrade", running: "1.30", target: "1.29", expectedError: "eks does not downgrade clusters"},
+		{name: "skip", running: "1.30", target: "1.32", expectedError: "upgrade to 1.31 first"},
+		{name: "major change", running: "1.30", target: "2.0", expectedError: "changes the major version"},
+		{name: "patch version", running: "1.30", target: "1.31.2", expectedError: `spec.cluster.kubernetesVersion: "1.31.2" is not a major.minor kubernetes version`},
+		{name: "bad running version", running: "v1.30", target: "1.31", expectedError: `running cluster version: "v1.30"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkUpgradeStep(test.running, test.target)
+
+			if test.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+				t.Fatalf("expected an error containing %q, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
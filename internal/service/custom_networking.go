@@ -0,0 +1,165 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"pulumi-eks/internal/types"
+	"sort"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v3/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// eniConfigLabel is the node label the vpc cni reads to pick the ENIConfig of
+// a node, every ENIConfig being named after its availability zone.
+const eniConfigLabel = "topology.kubernetes.io/zone"
+
+type CustomNetworking struct {
+	ctx        *pulumi.Context
+	networking types.Networking
+	cluster    types.Cluster
+
+	vpcCniAddon *eks.Addon
+}
+
+func NewCustomNetworking(ctx *pulumi.Context, networking types.Networking, cluster types.Cluster) *CustomNetworking {
+	return &CustomNetworking{
+		ctx:        ctx,
+		networking: networking,
+		cluster:    cluster,
+	}
+}
+
+// Run turns on the vpc cni custom networking when the vpc has pod subnets.
+// Without them nothing is created, but the service is not skipped either so
+// the node groups consuming it still run.
+func (c *CustomNetworking) Run(dependency *types.InterServicesDependencies) error {
+	if len(dependency.PodSubnetsByAZ) == 0 {
+		return nil
+	}
+
+	steps := []func() error{
+		func() error { return c.configureVpcCni(dependency) },
+		func() error { return c.createENIConfigs(dependency) },
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CustomNetworking) Produces() []types.Dependency {
+	return []types.Dependency{types.CustomNetworkingDependency}
+}
+
+func (c *CustomNetworking) Consumes() []types.Dependency {
+	return []types.Dependency{
+		types.SubnetsDependency,
+		types.ClusterDependency,
+	}
+}
+
+func (c *CustomNetworking) configureVpcCni(dependency *types.InterServicesDependencies) error {
+	configurationValues, err := json.Marshal(map[string]interface{}{
+		"env": map[string]string{
+			"AWS_VPC_K8S_CNI_CUSTOM_NETWORK_CFG": "true",
+			"ENI_CONFIG_LABEL_DEF":               eniConfigLabel,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	addonVersion, err := c.vpcCniVersion()
+	if err != nil {
+		return err
+	}
+
+	addonUniqueName := fmt.Sprintf("%s-vpc-cni-addon", c.networking.Name)
+
+	vpcCniAddon, err := eks.NewAddon(c.ctx, addonUniqueName, &eks.AddonArgs{
+		AddonName:                pulumi.String("vpc-cni"),
+		AddonVersion:             addonVersion,
+		ClusterName:              dependency.ClusterOutput.EKSCluster.Name,
+		ConfigurationValues:      pulumi.String(string(configurationValues)),
+		ResolveConflictsOnCreate: pulumi.String("OVERWRITE"),
+		ResolveConflictsOnUpdate: pulumi.String("OVERWRITE"),
+	}, pulumi.DependsOn([]pulumi.Resource{dependency.ClusterOutput.EKSCluster}))
+	if err != nil {
+		return err
+	}
+
+	c.vpcCniAddon = vpcCniAddon
+	dependency.CustomNetworking = append(dependency.CustomNetworking, vpcCniAddon)
+
+	return nil
+}
+
+// vpcCniVersion leaves the installed version alone outside upgrade mode. In
+// upgrade mode the cni moves to the default version of the new kubernetes
+// version along with the control plane.
+func (c *CustomNetworking) vpcCniVersion() (pulumi.StringPtrInput, error) {
+	if !c.cluster.Upgrade {
+		return nil, nil
+	}
+
+	addonVersion, err := resolveAddonVersion(c.ctx, "vpc-cni", c.cluster, "")
+	if err != nil {
+		return nil, err
+	}
+
+	c.ctx.Export("vpcCniVersion", pulumi.String(addonVersion))
+
+	return pulumi.String(addonVersion), nil
+}
+
+// createENIConfigs creates one ENIConfig per availability zone, giving the
+// pods of the nodes in that zone their ips from its pod subnet.
+func (c *CustomNetworking) createENIConfigs(dependency *types.InterServicesDependencies) error {
+	provider, err := kubernetes.NewProvider(c.ctx, "kubernetes-provider-custom-networking", &kubernetes.ProviderArgs{
+		Kubeconfig: dependency.ClusterOutput.KubeConfig,
+	})
+	if err != nil {
+		return err
+	}
+
+	clusterSecurityGroupID := dependency.ClusterOutput.EKSCluster.VpcConfig.ClusterSecurityGroupId().Elem()
+
+	availabilityZones := make([]string, 0, len(dependency.PodSubnetsByAZ))
+	for availabilityZone := range dependency.PodSubnetsByAZ {
+		availabilityZones = append(availabilityZones, availabilityZone)
+	}
+	sort.Strings(availabilityZones)
+
+	for _, availabilityZone := range availabilityZones {
+		podSubnet := dependency.PodSubnetsByAZ[availabilityZone]
+
+		eniConfig, err := apiextensions.NewCustomResource(c.ctx, fmt.Sprintf("eniconfig-%s", availabilityZone), &apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("crd.k8s.amazonaws.com/v1alpha1"),
+			Kind:       pulumi.String("ENIConfig"),
+			Metadata: metav1.ObjectMetaArgs{
+				Name: pulumi.String(availabilityZone),
+			},
+			OtherFields: map[string]interface{}{
+				"spec": pulumi.Map{
+					"subnet":         podSubnet.ID(),
+					"securityGroups": pulumi.StringArray{clusterSecurityGroupID},
+				},
+			},
+		}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{c.vpcCniAddon}))
+		if err != nil {
+			return err
+		}
+
+		dependency.CustomNetworking = append(dependency.CustomNetworking, eniConfig)
+	}
+
+	return nil
+}
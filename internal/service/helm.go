@@ -7,6 +7,7 @@ import (
 	"pulumi-eks/internal/service/shared"
 	"pulumi-eks/internal/types"
 	"pulumi-eks/pkg/generic"
+	cfgreader "pulumi-eks/pkg/read"
 	"strings"
 
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
@@ -22,12 +23,14 @@ import (
 type Extensions struct {
 	ctx            *pulumi.Context
 	helmComponents types.HelmChartsComponentes
+	secrets        cfgreader.Secrets
 }
 
-func NewExtensions(ctx *pulumi.Context, components types.HelmChartsComponentes) *Extensions {
+func NewExtensions(ctx *pulumi.Context, components types.HelmChartsComponentes, secrets cfgreader.Secrets) *Extensions {
 	return &Extensions{
 		ctx:            ctx,
 		helmComponents: components,
+		secrets:        secrets,
 	}
 }
 
@@ -35,6 +38,18 @@ func (e *Extensions) Run(dependency *types.InterServicesDependencies) error {
 	return e.applyHelmCharts(dependency)
 }
 
+func (e *Extensions) Produces() []types.Dependency {
+	return nil
+}
+
+func (e *Extensions) Consumes() []types.Dependency {
+	return []types.Dependency{
+		types.ClusterDependency,
+		types.NodeGroupsDependency,
+		types.OIDCProviderDependency,
+	}
+}
+
 func (e *Extensions) applyHelmCharts(dependency *types.InterServicesDependencies) error {
 	dependsOn := shared.RetrieveDependsOnList(dependency)
 
@@ -62,19 +77,19 @@ func (e *Extensions) applyHelmCharts(dependency *types.InterServicesDependencies
 			Chart:           pulumi.String(component.Chart),
 			Namespace:       pulumi.StringPtr(component.Namespace),
 			CreateNamespace: pulumi.BoolPtr(component.CreateNamespace),
-			SkipCrds:        pulumi.BoolPtr(component.SkipCirds),
+			SkipCrds:        pulumi.BoolPtr(component.SkipCrds),
 			Version:         pulumi.StringPtr(*component.Version),
 			RepositoryOpts: helmv3.RepositoryOptsArgs{
 				Repo: pulumi.String(component.Repository),
 			},
-			Values: pulumi.ToMap(helmValue),
+			Values: e.secrets.ToMap(helmValue),
 		}, pulumi.DependsOn(dependsOn), pulumi.Provider(provider))
 
 		// _, err = helmv4.NewChart(e.ctx, component.Name, &helmv4.ChartArgs{
 		// 	Name:      pulumi.String(component.Name),
 		// 	Chart:     pulumi.String(component.Name),
 		// 	Namespace: pulumi.String(component.Namespace),
-		// 	SkipCrds:  pulumi.BoolPtr(component.SkipCirds),
+		// 	SkipCrds:  pulumi.BoolPtr(component.SkipCrds),
 		// 	Version:   pulumi.String(*component.Version),
 		// 	RepositoryOpts: helmv4.RepositoryOptsArgs{
 		// 		Repo: pulumi.String(component.Repository),
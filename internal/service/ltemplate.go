@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
-	"html/template"
+	"net/netip"
 	"pulumi-eks/internal/types"
 	"strings"
+	"text/template"
 
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -33,28 +34,50 @@ func (ag *LaunchTemplate) Run(dependency *types.InterServicesDependencies) error
 	return ag.launchTemplate(dependency)
 }
 
+func (ag *LaunchTemplate) Produces() []types.Dependency {
+	return []types.Dependency{types.LaunchTemplatesDependency}
+}
+
+func (ag *LaunchTemplate) Consumes() []types.Dependency {
+	return []types.Dependency{types.ClusterDependency}
+}
+
 func (ag *LaunchTemplate) launchTemplate(dependency *types.InterServicesDependencies) error {
 
 	var launchTemplateOutputMap = make(map[string]types.NodeGroupMetadata, len(ag.nodes))
+	var nodeGroupAmis = make(pulumi.StringMap, len(ag.nodes))
 
 	for n, node := range ag.nodes {
 		launchTemplateUniqueName := fmt.Sprintf("%s-lt-%d", node.Name, n)
 
 		clusterUserData := createLtUserData(
 			dependency.ClusterOutput,
+			clusterIpFamily(ag.cluster),
 			node.Name,
+			nodeAmiFamily(node),
 		)
 
+		imageId, err := ag.nodeImageId(node)
+		if err != nil {
+			return err
+		}
+
+		nodeGroupAmis[node.Name] = pulumi.String(imageId)
+
+		if err := ag.reportNodeAmiChange(node, launchTemplateUniqueName, imageId); err != nil {
+			return err
+		}
+
 		launchTemplateOutput, err := ec2.NewLaunchTemplate(ag.ctx, launchTemplateUniqueName, &ec2.LaunchTemplateArgs{
 			Name:                 pulumi.String(launchTemplateUniqueName),
 			UpdateDefaultVersion: pulumi.Bool(true),
-			ImageId:              pulumi.String(node.ImageId),
+			ImageId:              pulumi.String(imageId),
 			InstanceType:         pulumi.String(node.InstanceType),
 			UserData:             clusterUserData.ToStringPtrOutput(),
 
 			BlockDeviceMappings: ec2.LaunchTemplateBlockDeviceMappingArray{
 				ec2.LaunchTemplateBlockDeviceMappingArgs{
-					DeviceName: pulumi.String("/dev/xvda"),
+					DeviceName: pulumi.String(nodeDataDeviceName(node)),
 					Ebs: ec2.LaunchTemplateBlockDeviceMappingEbsArgs{
 						VolumeSize:          pulumi.Int(30),
 						VolumeType:          pulumi.String("gp3"),
@@ -100,15 +123,33 @@ func (ag *LaunchTemplate) launchTemplate(dependency *types.InterServicesDependen
 	}
 	dependency.LaunchTemplateOutputList = launchTemplateOutputMap
 
+	// shows up in the preview whenever a node group is about to change ami
+	ag.ctx.Export("nodeGroupAmis", nodeGroupAmis)
+
 	return nil
 }
 
-func createLtUserData(clusterOutput types.ClusterOutput, nodeGroupName string) pulumi.StringOutput {
+// nodeDataDeviceName is the volume holding the containers, bottlerocket keeps
+// its os on a small /dev/xvda and the data on /dev/xvdb.
+func nodeDataDeviceName(node types.NodeGroups) string {
+	if nodeAmiFamily(node) == types.AMI_FAMILY_BOTTLEROCKET {
+		return "/dev/xvdb"
+	}
+
+	return "/dev/xvda"
+}
+
+func createLtUserData(clusterOutput types.ClusterOutput, ipFamily string, nodeGroupName string, amiFamily string) pulumi.StringOutput {
+	serviceCidr := clusterOutput.EKSCluster.KubernetesNetworkConfig.ServiceIpv4Cidr()
+	if ipFamily == types.IP_FAMILY_IPV6 {
+		serviceCidr = clusterOutput.EKSCluster.KubernetesNetworkConfig.ServiceIpv6Cidr()
+	}
+
 	return pulumi.All(
 		clusterOutput.EKSCluster.Name,
 		clusterOutput.EKSCluster.CertificateAuthority.Data(),
 		clusterOutput.EKSCluster.Endpoint,
-		clusterOutput.EKSCluster.KubernetesNetworkConfig.ServiceIpv4Cidr(),
+		serviceCidr,
 	).
 		ApplyT(func(args []interface{}) (string, error) {
 			clusterName := args[0].(string)
@@ -116,6 +157,10 @@ func createLtUserData(clusterOutput types.ClusterOutput, nodeGroupName string) p
 			endpoint := args[2].(string)
 			clusterCidr := args[3].(*string)
 
+			if amiFamily == types.AMI_FAMILY_BOTTLEROCKET {
+				return buildBottlerocketUserData(clusterName, *ca, endpoint, *clusterCidr, strings.ToUpper(nodeGroupName))
+			}
+
 			return buildLauncTemplateUserData(clusterName, *ca, endpoint, *clusterCidr, strings.ToUpper(nodeGroupName))
 		}).(pulumi.StringOutput)
 }
@@ -177,3 +222,66 @@ systemctl enable amazon-ssm-agent && systemctl start amazon-ssm-agent
 
 	return base64.StdEncoding.EncodeToString(r.Bytes()), nil
 }
+
+// buildBottlerocketUserData writes the bottlerocket settings, which replace
+// the nodeadm config of al2023. The ssm agent already runs in the bottlerocket
+// control container.
+func buildBottlerocketUserData(clusterName, clusterCA, clusterAPIServerURL, clusterCIDR, nodeGroupName string) (string, error) {
+	const BOTTLEROCKET_USERDATA = `[settings.kubernetes]
+api-server = "{{ .ApiServerUrl }}"
+cluster-certificate = "{{ .ClusterCA }}"
+cluster-name = "{{ .ClusterName }}"
+cluster-dns-ip = "{{ .ClusterDNSIP }}"
+
+[settings.kubernetes.node-labels]
+"eks.amazonaws.com/nodegroup" = "{{ .NodeGroupName }}"
+`
+
+	clusterDNSIP, err := clusterDNSIP(clusterCIDR)
+	if err != nil {
+		return "", err
+	}
+
+	ltData := struct {
+		ClusterName   string
+		ClusterCA     string
+		ApiServerUrl  string
+		ClusterDNSIP  string
+		NodeGroupName string
+	}{
+		ClusterName:   clusterName,
+		ClusterCA:     clusterCA,
+		ApiServerUrl:  clusterAPIServerURL,
+		ClusterDNSIP:  clusterDNSIP,
+		NodeGroupName: nodeGroupName,
+	}
+
+	tmpl, err := template.New("bottlerocketUserData").Parse(BOTTLEROCKET_USERDATA)
+	if err != nil {
+		return "", err
+	}
+
+	var r bytes.Buffer
+	if err := tmpl.Execute(&r, ltData); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(r.Bytes()), nil
+}
+
+// clusterDNSIP is the tenth address of the service cidr where eks runs the
+// cluster dns, 172.20.0.10 or fd00:1234::a. Bottlerocket only works it out on
+// its own for ipv4 clusters.
+func clusterDNSIP(serviceCidr string) (string, error) {
+	prefix, err := netip.ParsePrefix(serviceCidr)
+	if err != nil {
+		return "", fmt.Errorf("service cidr %q: %w", serviceCidr, err)
+	}
+
+	address := prefix.Masked().Addr().AsSlice()
+	address[len(address)-1] += 10
+
+	dnsIP, _ := netip.AddrFromSlice(address)
+
+	return dnsIP.String(), nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ssm"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// nodeImageId returns the pinned imageId, or looks up the eks optimized ami
+// of the node group family for the cluster kubernetes version in the ssm
+// public parameters, so a version bump moves the nodes to the new ami.
+func (ag *LaunchTemplate) nodeImageId(node types.NodeGroups) (string, error) {
+	if node.ImageId != "" {
+		return node.ImageId, nil
+	}
+
+	parameterName := nodeAmiParameter(node, ag.cluster.KubernetesVersion)
+
+	ami, err := ssm.LookupParameter(ag.ctx, &ssm.LookupParameterArgs{Name: parameterName})
+	if err != nil {
+		return "", fmt.Errorf("looking up the %s node group ami from %s: %w", node.Name, parameterName, err)
+	}
+
+	return ami.Value, nil
+}
+
+// reportNodeAmiChange warns, in the preview and in the update, when the
+// recommended ami moved since the launch template was last updated, the
+// default floating with every ami aws publishes.
+func (ag *LaunchTemplate) reportNodeAmiChange(node types.NodeGroups, launchTemplateName, imageId string) error {
+	if node.ImageId != "" || node.AmiReleaseVersion != "" {
+		return nil
+	}
+
+	current, err := ec2.LookupLaunchTemplate(ag.ctx, &ec2.LookupLaunchTemplateArgs{Name: pulumi.StringRef(launchTemplateName)})
+	if err != nil {
+		// a launch template not created yet has nothing to compare with
+		if isNotFound(err) {
+			return nil
+		}
+
+		return ag.ctx.Log.Warn(fmt.Sprintf(
+			"node group %s: cannot compare its ami with the launch template %s: %v", node.Name, launchTemplateName, err,
+		), nil)
+	}
+
+	if current.ImageId == imageId {
+		return nil
+	}
+
+	return ag.ctx.Log.Warn(fmt.Sprintf(
+		"node group %s moves from %s to %s, the new recommended %s ami of kubernetes %s, set amiReleaseVersion to pin it",
+		node.Name, current.ImageId, imageId, nodeAmiFamily(node), ag.cluster.KubernetesVersion,
+	), nil)
+}
+
+// isNotFound reports whether a data source lookup failed because the resource
+// does not exist.
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "no matching") || strings.Contains(err.Error(), "NotFound")
+}
+
+// nodeAmiParameter builds the ssm parameter of the recommended ami, or of the
+// amiReleaseVersion release when pinned, e.g.
+// /aws/service/eks/optimized-ami/1.31/amazon-linux-2023/arm64/standard/recommended/image_id
+// /aws/service/bottlerocket/aws-k8s-1.31/x86_64/1.26.1/image_id
+func nodeAmiParameter(node types.NodeGroups, kubernetesVersion string) string {
+	if nodeAmiFamily(node) == types.AMI_FAMILY_BOTTLEROCKET {
+		release := node.AmiReleaseVersion
+		if release == "" {
+			release = "latest"
+		}
+
+		return fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/x86_64/%s/image_id", kubernetesVersion, release)
+	}
+
+	arch, variant := "x86_64", "standard"
+
+	switch nodeAmiFamily(node) {
+	case types.AMI_FAMILY_AL2023_NVIDIA:
+		variant = "nvidia"
+	case types.AMI_FAMILY_AL2023_ARM64:
+		arch = "arm64"
+	}
+
+	image := "recommended"
+	if node.AmiReleaseVersion != "" {
+		image = fmt.Sprintf("amazon-eks-node-al2023-%s-%s-%s-%s", arch, variant, kubernetesVersion, node.AmiReleaseVersion)
+	}
+
+	return fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/%s/%s/%s/image_id", kubernetesVersion, arch, variant, image)
+}
+
+func nodeAmiFamily(node types.NodeGroups) string {
+	if node.AmiFamily == "" {
+		return types.AMI_FAMILY_AL2023
+	}
+
+	return node.AmiFamily
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"pulumi-eks/internal/types"
+)
+
+func TestNodeAmiParameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     types.NodeGroups
+		expected string
+	}{
+		{
+			name:     "al2023 default",
+			node:     types.NodeGroups{},
+			expected: "/aws/service/eks/optimized-ami/1.31/amazon-linux-2023/x86_64/standard/recommended/image_id",
+		},
+		{
+			name:     "al2023 pinned",
+			node:     types.NodeGroups{AmiFamily: types.AMI_FAMILY_AL2023, AmiReleaseVersion: "v20241024"},
+			expected: "/aws/service/eks/optimized-ami/1.31/amazon-linux-2023/x86_64/standard/amazon-eks-node-al2023-x86_64-standard-1.31-v20241024/image_id",
+		},
+		{
+			name:     "al2023 nvidia",
+			node:     types.NodeGroups{AmiFamily: types.AMI_FAMILY_AL2023_NVIDIA},
+			expected: "/aws/service/eks/optimized-ami/1.31/amazon-linux-2023/x86_64/nvidia/recommended/image_id",
+		},
+		{
+			name:     "al2023 nvidia pinned",
+			node:     types.NodeGroups{AmiFamily: types.AMI_FAMILY_AL2023_NVIDIA, AmiReleaseVersion: "v20241024"},
+			expected: "/aws/service/eks/optimized-ami/1.31/amazon-linux-2023/x86_64/nvidia/amazon-eks-node-al2023-x86_64-nvidia-1.31-v20241024/image_id",
+		},
+		{
+			name:     "al2023 arm64",
+			node:     types.NodeGroups{AmiFamily: types.AMI_FAMILY_AL2023_ARM64},
+			expected: "/aws/service/eks/optimized-ami/1.31/amazon-linux-2023/arm64/standard/recommended/image_id",
+		},
+		{
+			name:     "al2023 arm64 pinned",
+			node:     types.NodeGroups{AmiFamily: types.AMI_FAMILY_AL2023_ARM64, AmiReleaseVersion: "v20241024"},
+			expected: "/aws/service/eks/optimized-ami/1.31/amazon-linux-2023/arm64/standard/amazon-eks-node-al2023-arm64-standard-1.31-v20241024/image_id",
+		},
+		{
+			name:     "bottlerocket",
+			node:     types.NodeGroups{AmiFamily: types.AMI_FAMILY_BOTTLEROCKET},
+			expected: "/aws/service/bottlerocket/aws-k8s-1.31/x86_64/latest/image_id",
+		},
+		{
+			name:     "bottlerocket pinned",
+			node:     types.NodeGroups{AmiFamily: types.AMI_FAMILY_BOTTLEROCKET, AmiReleaseVersion: "1.26.1"},
+			expected: "/aws/service/bottlerocket/aws-k8s-1.31/x86_64/1.26.1/image_id",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := nodeAmiParameter(test.node, "1.31"); actual != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := map[string]bool{
+		"invoking aws:ec2/getLaunchTemplate:getLaunchTemplate: reading EC2 Launch Template: no matching EC2 Launch Template found":  true,
+		"InvalidLaunchTemplateName.NotFoundException: At least one of the launch templates specified in the request does not exist": true,
+		"operation error EC2: DescribeLaunchTemplates, https response error StatusCode: 403, UnauthorizedOperation":                 false,
+	}
+
+	for message, expected := range tests {
+		if actual := isNotFound(errors.New(message)); actual != expected {
+			t.Errorf("isNotFound(%q): expected %t, got %t", message, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestClusterDNSIP(t *testing.T) {
+	tests := map[string]string{
+		"172.20.0.0/16":        "172.20.0.10",
+		"10.100.0.0/16":        "10.100.0.10",
+		"fd12:3456:789a::/108": "fd12:3456:789a::a",
+	}
+
+	for serviceCidr, expected := range tests {
+		actual, err := clusterDNSIP(serviceCidr)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", serviceCidr, err)
+		}
+
+		if actual != expected {
+			t.Errorf("%s: expected %s, got %s", serviceCidr, expected, actual)
+		}
+	}
+}
+
+func TestBottlerocketUserDataSetsClusterDNSIP(t *testing.T) {
+	userData, err := buildBottlerocketUserData("apps", "Q0E=", "https://api.example", "fd12:3456:789a::/108", "NG")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	settings, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		t.Fatalf("user data is not base64: %v", err)
+	}
+
+	if !strings.Contains(string(settings), `cluster-dns-ip = "fd12:3456:789a::a"`) {
+		t.Errorf("expected the ipv6 cluster dns ip in the settings, got:\n%s", settings)
+	}
+}
+
+func TestUserDataKeepsTheClusterCAVerbatim(t *testing.T) {
+	const clusterCA = "LS0t+ab/c=="
+
+	builders := map[string]func() (string, error){
+		"al2023": func() (string, error) {
+			return buildLauncTemplateUserData("apps", clusterCA, "https://api.example", "172.20.0.0/16", "NG")
+		},
+		"bottlerocket": func() (string, error) {
+			return buildBottlerocketUserData("apps", clusterCA, "https://api.example", "172.20.0.0/16", "NG")
+		},
+	}
+
+	for name, build := range builders {
+		userData, err := build()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(userData)
+		if err != nil {
+			t.Fatalf("%s: user data is not base64: %v", name, err)
+		}
+
+		if !strings.Contains(string(decoded), clusterCA) {
+			t.Errorf("%s: expected the cluster ca %s verbatim, got:\n%s", name, clusterCA, decoded)
+		}
+	}
+}
@@ -13,7 +13,9 @@ type Networking struct {
 	ctx        *pulumi.Context
 	networking types.Networking
 
-	vpc *ec2.Vpc
+	vpc                 *ec2.Vpc
+	existingVpcID       string
+	secondaryCidrBlocks []pulumi.Resource
 
 	networkingConfigMap NetworkingConfigMap
 }
@@ -29,13 +31,28 @@ func NewNetworking(ctx *pulumi.Context, n types.Networking) *Networking {
 func (v *Networking) Run(dependency *types.InterServicesDependencies) error {
 	steps := []func() error{
 		func() error { return v.networkingVpc() },
+		func() error { return v.networkingSecondaryCidrBlocks() },
 		func() error { return v.networkingSubnets(dependency) },
 		func() error { return v.networkingInternetGateway() },
+		func() error { return v.networkingEgressOnlyInternetGateway() },
 		func() error { return v.networkingEIPs() },
 		func() error { return v.networkingNatGateway() },
 		func() error { return v.networkingRouteTable() },
 		func() error { return v.networkingRoutes() },
+		func() error { return v.networkingIpv6Routes() },
 		func() error { return v.networkingRouteTableAndSubnets() },
+		func() error { return v.networkingNetworkAcls() },
+		func() error { return v.networkingVpcEndpoints() },
+		func() error { return v.networkingConnectivity() },
+		func() error { return v.networkingFlowLogs() },
+	}
+
+	if v.networking.Existing != nil {
+		steps = []func() error{
+			func() error { return v.existingVpc() },
+			func() error { return v.existingSubnets(dependency) },
+			func() error { return v.networkingFlowLogs() },
+		}
 	}
 
 	for _, step := range steps {
@@ -47,6 +64,14 @@ func (v *Networking) Run(dependency *types.InterServicesDependencies) error {
 	return nil
 }
 
+func (v *Networking) Produces() []types.Dependency {
+	return []types.Dependency{types.SubnetsDependency}
+}
+
+func (v *Networking) Consumes() []types.Dependency {
+	return nil
+}
+
 type NetworkingConfigMap map[string]NetworkingConfig
 
 type NetworkingConfig struct {
@@ -55,6 +80,7 @@ type NetworkingConfig struct {
 	EIP                    *ec2.Eip
 	Type                   types.SubnetType
 	InternetGateway        *ec2.InternetGateway
+	EgressOnlyGateway      *ec2.EgressOnlyInternetGateway
 	NatGateway             *ec2.NatGateway
 	RouteTable             *ec2.RouteTable
 	Route                  *ec2.Route
@@ -62,10 +88,11 @@ type NetworkingConfig struct {
 
 func (v *Networking) networkingVpc() error {
 	vpc, err := ec2.NewVpc(v.ctx, v.networking.Name, &ec2.VpcArgs{
-		Tags:               pulumi.StringMap{"Name": pulumi.String(v.networking.Name)},
-		CidrBlock:          pulumi.String(v.networking.CidrBlock),
-		EnableDnsSupport:   pulumi.Bool(true),
-		EnableDnsHostnames: pulumi.Bool(true),
+		Tags:                         pulumi.StringMap{"Name": pulumi.String(v.networking.Name)},
+		CidrBlock:                    pulumi.String(v.networking.CidrBlock),
+		EnableDnsSupport:             pulumi.Bool(true),
+		EnableDnsHostnames:           pulumi.Bool(true),
+		AssignGeneratedIpv6CidrBlock: pulumi.Bool(v.networking.Ipv6),
 	})
 
 	v.vpc = vpc
@@ -73,6 +100,24 @@ func (v *Networking) networkingVpc() error {
 	return err
 }
 
+func (v *Networking) networkingSecondaryCidrBlocks() error {
+	for i, cidrBlock := range v.networking.SecondaryCidrBlocks {
+		associationUniqueName := fmt.Sprintf("%s-cidr-%d", v.networking.Name, i)
+
+		association, err := ec2.NewVpcIpv4CidrBlockAssociation(v.ctx, associationUniqueName, &ec2.VpcIpv4CidrBlockAssociationArgs{
+			VpcId:     v.vpc.ID(),
+			CidrBlock: pulumi.String(cidrBlock),
+		})
+		if err != nil {
+			return err
+		}
+
+		v.secondaryCidrBlocks = append(v.secondaryCidrBlocks, association)
+	}
+
+	return nil
+}
+
 func pulumiStringMapSubnetTag(name string, subnetInputTags map[string]interface{}) pulumi.StringMap {
 	result := make(pulumi.StringMap)
 
@@ -95,28 +140,28 @@ func pulumiStringMapSubnetTag(name string, subnetInputTags map[string]interface{
 func (v *Networking) networkingSubnets(d *types.InterServicesDependencies) error {
 	var publicSubnet []*ec2.Subnet
 	var privateSubnet []*ec2.Subnet
+	var isolatedSubnet []*ec2.Subnet
+	var podSubnetByAZ = make(map[string]*ec2.Subnet)
+	var publicSubnetByAZ = make(map[string]*ec2.Subnet)
 	var sharedSubnetsBetweenResources = make(map[types.SubnetType][]*ec2.Subnet)
+	var subnetsByName = make(map[string]*ec2.Subnet, len(v.networking.Subnets))
 
-	for _, subnet := range v.networking.Subnets {
+	for i, subnet := range v.networking.Subnets {
 		if !subnet.PublicIpOnLaunch {
 			continue
 		}
 
-		subnetTags := pulumiStringMapSubnetTag(subnet.Name, subnet.Tags)
-
-		subnetOutput, err := ec2.NewSubnet(v.ctx, subnet.Name, &ec2.SubnetArgs{
-			VpcId:               v.vpc.ID(),
-			CidrBlock:           pulumi.String(subnet.CidrBlock),
-			Tags:                subnetTags,
-			MapPublicIpOnLaunch: pulumi.Bool(subnet.PublicIpOnLaunch),
-			AvailabilityZone:    pulumi.String(subnet.AvailabilityZone),
-		})
-
+		subnetOutput, err := v.createSubnet(i, subnet)
 		if err != nil {
 			return err
 		}
 
+		if _, exists := publicSubnetByAZ[subnet.AvailabilityZone]; !exists {
+			publicSubnetByAZ[subnet.AvailabilityZone] = subnetOutput
+		}
+
 		publicSubnet = append(publicSubnet, subnetOutput)
+		subnetsByName[subnet.Name] = subnetOutput
 		v.networkingConfigMap[subnet.Name] = NetworkingConfig{
 			Subnet: subnetOutput,
 			Type:   types.PUBLIC_SUBNET,
@@ -124,20 +169,16 @@ func (v *Networking) networkingSubnets(d *types.InterServicesDependencies) error
 	}
 
 	for i, subnet := range v.networking.Subnets {
-		if subnet.PublicIpOnLaunch {
+		if subnet.PublicIpOnLaunch || subnet.Isolated {
 			continue
 		}
 
-		subnetTags := pulumiStringMapSubnetTag(subnet.Name, subnet.Tags)
-
-		subnetOutput, err := ec2.NewSubnet(v.ctx, subnet.Name, &ec2.SubnetArgs{
-			VpcId:               v.vpc.ID(),
-			CidrBlock:           pulumi.String(subnet.CidrBlock),
-			Tags:                subnetTags,
-			MapPublicIpOnLaunch: pulumi.Bool(subnet.PublicIpOnLaunch),
-			AvailabilityZone:    pulumi.String(subnet.AvailabilityZone),
-		})
+		natGatewayPublicSubnet, err := v.natGatewayPublicSubnet(subnet, publicSubnet, publicSubnetByAZ)
+		if err != nil {
+			return err
+		}
 
+		subnetOutput, err := v.createSubnet(i, subnet)
 		if err != nil {
 			return err
 		}
@@ -145,35 +186,138 @@ func (v *Networking) networkingSubnets(d *types.InterServicesDependencies) error
 		v.networkingConfigMap[subnet.Name] = NetworkingConfig{
 			Subnet:                 subnetOutput,
 			Type:                   types.PRIVATE_SUBNET,
-			NatGatewayPublicSubnet: publicSubnet[i],
+			NatGatewayPublicSubnet: natGatewayPublicSubnet,
+		}
+
+		subnetsByName[subnet.Name] = subnetOutput
+
+		// pod subnets are routed like any private subnet but kept apart,
+		// nodes and load balancers never land in them
+		if subnet.Pods {
+			podSubnetByAZ[subnet.AvailabilityZone] = subnetOutput
+			continue
 		}
 
 		privateSubnet = append(privateSubnet, subnetOutput)
 	}
 
+	for i, subnet := range v.networking.Subnets {
+		if !subnet.Isolated {
+			continue
+		}
+
+		subnetOutput, err := v.createSubnet(i, subnet)
+		if err != nil {
+			return err
+		}
+
+		v.networkingConfigMap[subnet.Name] = NetworkingConfig{
+			Subnet: subnetOutput,
+			Type:   types.ISOLATED_SUBNET,
+		}
+
+		isolatedSubnet = append(isolatedSubnet, subnetOutput)
+		subnetsByName[subnet.Name] = subnetOutput
+	}
+
 	sharedSubnetsBetweenResources[types.PRIVATE_SUBNET] = privateSubnet
 	sharedSubnetsBetweenResources[types.PUBLIC_SUBNET] = publicSubnet
+	sharedSubnetsBetweenResources[types.ISOLATED_SUBNET] = isolatedSubnet
 
 	d.Subnets = sharedSubnetsBetweenResources
+	d.SubnetsByName = subnetsByName
+	d.PodSubnetsByAZ = podSubnetByAZ
 
 	return nil
 }
 
+func (v *Networking) createSubnet(i int, subnet types.Subnets) (*ec2.Subnet, error) {
+	subnetTags := pulumiStringMapSubnetTag(subnet.Name, subnet.Tags)
+
+	subnetArgs := &ec2.SubnetArgs{
+		VpcId:               v.vpc.ID(),
+		CidrBlock:           pulumi.String(subnet.CidrBlock),
+		Tags:                subnetTags,
+		MapPublicIpOnLaunch: pulumi.Bool(subnet.PublicIpOnLaunch),
+		AvailabilityZone:    pulumi.String(subnet.AvailabilityZone),
+	}
+
+	if v.networking.Ipv6 {
+		subnetArgs.Ipv6CidrBlock = subnetIpv6CidrBlock(v.vpc.Ipv6CidrBlock, ipv6NetNum(i, subnet))
+		subnetArgs.AssignIpv6AddressOnCreation = pulumi.Bool(true)
+	}
+
+	return ec2.NewSubnet(v.ctx, subnet.Name, subnetArgs, pulumi.DependsOn(v.secondaryCidrBlocks))
+}
+
+// natGatewayPublicSubnet returns the public subnet hosting the nat gateway of
+// a private subnet, or nil when the nat gateway mode is none.
+func (v *Networking) natGatewayPublicSubnet(subnet types.Subnets, publicSubnet []*ec2.Subnet, publicSubnetByAZ map[string]*ec2.Subnet) (*ec2.Subnet, error) {
+	switch v.natGatewayMode() {
+	case types.NAT_GATEWAY_NONE:
+		return nil, nil
+	case types.NAT_GATEWAY_SINGLE:
+		if len(publicSubnet) == 0 {
+			return nil, fmt.Errorf("nat gateway mode %s requires at least one public subnet", types.NAT_GATEWAY_SINGLE)
+		}
+		return publicSubnet[0], nil
+	}
+
+	natGatewayPublicSubnet, found := publicSubnetByAZ[subnet.AvailabilityZone]
+	if !found {
+		return nil, fmt.Errorf(
+			"private subnet %q has no public subnet in %s to place its nat gateway",
+			subnet.Name, subnet.AvailabilityZone,
+		)
+	}
+
+	return natGatewayPublicSubnet, nil
+}
+
+func (v *Networking) natGatewayMode() types.NatGatewayMode {
+	if v.networking.NatGateway.Mode == "" {
+		return types.NAT_GATEWAY_PER_SUBNET
+	}
+
+	return v.networking.NatGateway.Mode
+}
+
+// natGatewayUniqueName names the nat gateway resources (kind being eni or
+// ngw) serving the private subnet at index i. Subnets sharing a nat gateway
+// get the same name.
+func (v *Networking) natGatewayUniqueName(kind string, i int, subnet types.Subnets) string {
+	switch v.natGatewayMode() {
+	case types.NAT_GATEWAY_PER_AZ:
+		return fmt.Sprintf("%s-%s-%s", v.networking.Name, kind, subnet.AvailabilityZone)
+	case types.NAT_GATEWAY_SINGLE:
+		return fmt.Sprintf("%s-%s", v.networking.Name, kind)
+	}
+
+	return fmt.Sprintf("%s-%s-%d", v.networking.Name, kind, i)
+}
+
 func (v *Networking) networkingNatGateway() error {
+	natGatewayMap := make(map[string]*ec2.NatGateway)
 
 	for i, subnetConfig := range v.networking.Subnets {
 
-		if value, exists := v.networkingConfigMap[subnetConfig.Name]; exists && value.Type == types.PRIVATE_SUBNET {
-			natUniqueName := fmt.Sprintf("%s-ngw-%d", v.networking.Name, i)
+		if value, exists := v.networkingConfigMap[subnetConfig.Name]; exists && value.Type == types.PRIVATE_SUBNET && value.EIP != nil {
+			natUniqueName := v.natGatewayUniqueName("ngw", i, subnetConfig)
 
-			natGatewayOutput, err := ec2.NewNatGateway(v.ctx, natUniqueName, &ec2.NatGatewayArgs{
-				Tags:         pulumi.StringMap{"Name": pulumi.String(natUniqueName)},
-				AllocationId: value.EIP.ID(),
-				SubnetId:     value.NatGatewayPublicSubnet.ID(),
-			})
+			natGatewayOutput, created := natGatewayMap[natUniqueName]
+			if !created {
+				var err error
+				natGatewayOutput, err = ec2.NewNatGateway(v.ctx, natUniqueName, &ec2.NatGatewayArgs{
+					Tags:         pulumi.StringMap{"Name": pulumi.String(natUniqueName)},
+					AllocationId: value.EIP.ID(),
+					SubnetId:     value.NatGatewayPublicSubnet.ID(),
+				})
 
-			if err != nil {
-				return err
+				if err != nil {
+					return err
+				}
+
+				natGatewayMap[natUniqueName] = natGatewayOutput
 			}
 
 			value.NatGateway = natGatewayOutput
@@ -186,18 +330,26 @@ func (v *Networking) networkingNatGateway() error {
 }
 
 func (v *Networking) networkingEIPs() error {
+	eipMap := make(map[string]*ec2.Eip)
+
 	for i, subnetConfig := range v.networking.Subnets {
-		eniUniqueName := fmt.Sprintf("%s-eni-%d", v.networking.Name, i)
+		eniUniqueName := v.natGatewayUniqueName("eni", i, subnetConfig)
 
-		if config, exists := v.networkingConfigMap[subnetConfig.Name]; exists && config.Type == types.PRIVATE_SUBNET {
+		if config, exists := v.networkingConfigMap[subnetConfig.Name]; exists && config.Type == types.PRIVATE_SUBNET && config.NatGatewayPublicSubnet != nil {
 
-			natGatewayEIP, err := ec2.NewEip(v.ctx, eniUniqueName, &ec2.EipArgs{
-				Domain: pulumi.StringPtr("vpc"),
-				Tags:   pulumi.StringMap{"Name": pulumi.String(eniUniqueName)},
-			})
+			natGatewayEIP, created := eipMap[eniUniqueName]
+			if !created {
+				var err error
+				natGatewayEIP, err = ec2.NewEip(v.ctx, eniUniqueName, &ec2.EipArgs{
+					Domain: pulumi.StringPtr("vpc"),
+					Tags:   pulumi.StringMap{"Name": pulumi.String(eniUniqueName)},
+				})
 
-			if err != nil {
-				return err
+				if err != nil {
+					return err
+				}
+
+				eipMap[eniUniqueName] = natGatewayEIP
 			}
 
 			config.EIP = natGatewayEIP
@@ -257,6 +409,12 @@ func (v *Networking) networkingRoutes() error {
 		if config, exists := v.networkingConfigMap[subnet.Name]; exists {
 
 			routeUniqueName := fmt.Sprintf("%v-route-%d", subnet.Name, i)
+			// isolated subnets and private subnets without a nat gateway (mode
+			// none) keep a route table with no default route
+			if config.Type == types.ISOLATED_SUBNET || (config.Type == types.PRIVATE_SUBNET && config.NatGateway == nil) {
+				continue
+			}
+
 			if config.Type == types.PRIVATE_SUBNET {
 				routeOutput, err := ec2.NewRoute(v.ctx, routeUniqueName, &ec2.RouteArgs{
 					RouteTableId:         config.RouteTable.ID(),
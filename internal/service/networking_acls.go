@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	networkAclProtocolIcmp = "icmp"
+	// aws only matches icmp against ipv4, ipv6 rules need the icmpv6 protocol
+	// number instead.
+	networkAclProtocolIcmpv6 = "58"
+)
+
+// networkingNetworkAcls creates the network acl of every declared tier and
+// associates it to the subnets of that tier, taking them off the vpc default
+// network acl.
+func (v *Networking) networkingNetworkAcls() error {
+	networkAcls := []struct {
+		tier       string
+		subnetType types.SubnetType
+		acl        *types.NetworkAcl
+	}{
+		{"public", types.PUBLIC_SUBNET, v.networking.NetworkAcls.Public},
+		{"private", types.PRIVATE_SUBNET, v.networking.NetworkAcls.Private},
+		{"isolated", types.ISOLATED_SUBNET, v.networking.NetworkAcls.Isolated},
+	}
+
+	for _, networkAcl := range networkAcls {
+		if networkAcl.acl == nil {
+			continue
+		}
+
+		var subnetIds pulumi.StringArray
+		for _, subnet := range v.networking.Subnets {
+			if config, exists := v.networkingConfigMap[subnet.Name]; exists && config.Type == networkAcl.subnetType {
+				subnetIds = append(subnetIds, config.Subnet.ID())
+			}
+		}
+
+		var ingress ec2.NetworkAclIngressArray
+		for _, rule := range networkAcl.acl.Ingress {
+			ingress = append(ingress, ec2.NetworkAclIngressArgs(networkAclRuleArgs(rule)))
+		}
+
+		var egress ec2.NetworkAclEgressArray
+		for _, rule := range networkAcl.acl.Egress {
+			egress = append(egress, ec2.NetworkAclEgressArgs(networkAclRuleArgs(rule)))
+		}
+
+		naclUniqueName := fmt.Sprintf("%s-nacl-%s", v.networking.Name, networkAcl.tier)
+
+		_, err := ec2.NewNetworkAcl(v.ctx, naclUniqueName, &ec2.NetworkAclArgs{
+			Tags:      pulumi.StringMap{"Name": pulumi.String(naclUniqueName)},
+			VpcId:     v.vpc.ID(),
+			SubnetIds: subnetIds,
+			Ingress:   ingress,
+			Egress:    egress,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkAclRuleArgs converts a rule to the ingress args, the egress args
+// sharing the same fields.
+func networkAclRuleArgs(rule types.NetworkAclRule) ec2.NetworkAclIngressArgs {
+	args := ec2.NetworkAclIngressArgs{
+		RuleNo:   pulumi.Int(rule.RuleNumber),
+		Protocol: pulumi.String(rule.Protocol),
+		Action:   pulumi.String(rule.Action),
+		FromPort: pulumi.Int(rule.FromPort),
+		ToPort:   pulumi.Int(rule.ToPort),
+	}
+
+	if rule.CidrBlock != "" {
+		args.CidrBlock = pulumi.String(rule.CidrBlock)
+	}
+
+	if rule.Ipv6CidrBlock != "" {
+		args.Ipv6CidrBlock = pulumi.String(rule.Ipv6CidrBlock)
+
+		if rule.Protocol == networkAclProtocolIcmp {
+			args.Protocol = pulumi.String(networkAclProtocolIcmpv6)
+		}
+	}
+
+	// every icmp type and code, ports do not apply
+	if rule.Protocol == networkAclProtocolIcmp {
+		args.IcmpType = pulumi.Int(-1)
+		args.IcmpCode = pulumi.Int(-1)
+	}
+
+	return args
+}
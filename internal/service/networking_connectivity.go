@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2transitgateway"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// networkingConnectivity attaches the vpc to the transit gateway and to its
+// peers, then routes their remote cidr blocks from every private route table.
+// Routes are named after their subnet, adding a subnet only adds its routes.
+func (v *Networking) networkingConnectivity() error {
+	connectivity := v.networking.Connectivity
+
+	if connectivity.TransitGateway != nil {
+		attachment, err := v.transitGatewayAttachment(connectivity.TransitGateway)
+		if err != nil {
+			return err
+		}
+
+		err = v.remoteRoutes("tgw", connectivity.TransitGateway.RemoteCidrBlocks, func(args *ec2.RouteArgs) {
+			args.TransitGatewayId = attachment.TransitGatewayId
+		}, pulumi.DependsOn([]pulumi.Resource{attachment}))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, peering := range connectivity.Peerings {
+		peeringConnection, err := v.vpcPeeringConnection(peering)
+		if err != nil {
+			return err
+		}
+
+		err = v.remoteRoutes("pcx-"+peering.Name, peering.RemoteCidrBlocks, func(args *ec2.RouteArgs) {
+			args.VpcPeeringConnectionId = peeringConnection.ID()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transitGatewayAttachment attaches the first private subnet of every
+// availability zone, the transit gateway reaching the whole vpc from them.
+func (v *Networking) transitGatewayAttachment(transitGateway *types.TransitGatewayAttachment) (*ec2transitgateway.VpcAttachment, error) {
+	var attachmentSubnetIds pulumi.StringArray
+	var attachmentSubnetAZs = make(map[string]bool)
+
+	for _, subnet := range v.networking.Subnets {
+		config, exists := v.networkingConfigMap[subnet.Name]
+		if !exists || config.Type != types.PRIVATE_SUBNET || subnet.Pods || attachmentSubnetAZs[subnet.AvailabilityZone] {
+			continue
+		}
+
+		attachmentSubnetAZs[subnet.AvailabilityZone] = true
+		attachmentSubnetIds = append(attachmentSubnetIds, config.Subnet.ID())
+	}
+
+	if len(attachmentSubnetIds) == 0 {
+		return nil, fmt.Errorf("the transit gateway attachment requires at least one private subnet")
+	}
+
+	attachmentUniqueName := fmt.Sprintf("%s-tgw-attachment", v.networking.Name)
+
+	attachmentArgs := &ec2transitgateway.VpcAttachmentArgs{
+		Tags:             pulumi.StringMap{"Name": pulumi.String(attachmentUniqueName)},
+		TransitGatewayId: pulumi.String(transitGateway.ID),
+		VpcId:            v.vpc.ID(),
+		SubnetIds:        attachmentSubnetIds,
+	}
+
+	if v.networking.Ipv6 {
+		attachmentArgs.Ipv6Support = pulumi.String("enable")
+	}
+
+	return ec2transitgateway.NewVpcAttachment(v.ctx, attachmentUniqueName, attachmentArgs)
+}
+
+// vpcPeeringConnection requests the peering, accepting it right away when the
+// peer vpc is in the same account and region. Otherwise the peer side has to
+// accept it before traffic flows.
+func (v *Networking) vpcPeeringConnection(peering types.VpcPeering) (*ec2.VpcPeeringConnection, error) {
+	peeringUniqueName := fmt.Sprintf("%s-pcx-%s", v.networking.Name, peering.Name)
+
+	peeringArgs := &ec2.VpcPeeringConnectionArgs{
+		Tags:       pulumi.StringMap{"Name": pulumi.String(peeringUniqueName)},
+		VpcId:      v.vpc.ID(),
+		PeerVpcId:  pulumi.String(peering.PeerVpcID),
+		AutoAccept: pulumi.Bool(peering.PeerOwnerID == "" && peering.PeerRegion == ""),
+	}
+
+	if peering.PeerOwnerID != "" {
+		peeringArgs.PeerOwnerId = pulumi.String(peering.PeerOwnerID)
+	}
+
+	if peering.PeerRegion != "" {
+		peeringArgs.PeerRegion = pulumi.String(peering.PeerRegion)
+	}
+
+	return ec2.NewVpcPeeringConnection(v.ctx, peeringUniqueName, peeringArgs)
+}
+
+// remoteRoutes adds a route to every remote cidr block in the route table of
+// every private subnet, target filling in where the routes go.
+func (v *Networking) remoteRoutes(kind string, remoteCidrBlocks []string, target func(*ec2.RouteArgs), opts ...pulumi.ResourceOption) error {
+	for _, subnet := range v.networking.Subnets {
+		config, exists := v.networkingConfigMap[subnet.Name]
+		if !exists || config.Type != types.PRIVATE_SUBNET {
+			continue
+		}
+
+		for _, remoteCidrBlock := range remoteCidrBlocks {
+			routeArgs := &ec2.RouteArgs{
+				RouteTableId:         config.RouteTable.ID(),
+				DestinationCidrBlock: pulumi.String(remoteCidrBlock),
+			}
+			target(routeArgs)
+
+			_, err := ec2.NewRoute(v.ctx, remoteRouteUniqueName(subnet.Name, kind, remoteCidrBlock), routeArgs, opts...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func remoteRouteUniqueName(subnetName, kind, remoteCidrBlock string) string {
+	return fmt.Sprintf("%s-%s-%s", subnetName, kind, strings.NewReplacer(".", "-", "/", "-").Replace(remoteCidrBlock))
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// networkingVpcEndpoints creates the gateway endpoints on the private and
+// isolated route tables and the interface endpoints in one of those subnets
+// per availability zone, so nodes can reach the aws apis with no internet
+// egress.
+func (v *Networking) networkingVpcEndpoints() error {
+	endpoints := v.networking.VpcEndpoints
+	if len(endpoints.Gateway) == 0 && len(endpoints.Interface) == 0 {
+		return nil
+	}
+
+	region, err := aws.GetRegion(v.ctx, &aws.GetRegionArgs{})
+	if err != nil {
+		return err
+	}
+
+	var privateRouteTableIds []pulumi.StringOutput
+	var interfaceSubnetIds []pulumi.StringOutput
+	var interfaceSubnetAZs = make(map[string]bool)
+
+	for _, subnet := range v.networking.Subnets {
+		config, exists := v.networkingConfigMap[subnet.Name]
+		if !exists || config.Type == types.PUBLIC_SUBNET {
+			continue
+		}
+
+		privateRouteTableIds = append(privateRouteTableIds, config.RouteTable.ID().ToStringOutput())
+
+		if !subnet.Pods && !interfaceSubnetAZs[subnet.AvailabilityZone] {
+			interfaceSubnetAZs[subnet.AvailabilityZone] = true
+			interfaceSubnetIds = append(interfaceSubnetIds, config.Subnet.ID().ToStringOutput())
+		}
+	}
+
+	for _, service := range endpoints.Gateway {
+		endpointUniqueName := vpcEndpointUniqueName(v.networking.Name, service)
+
+		_, err := ec2.NewVpcEndpoint(v.ctx, endpointUniqueName, &ec2.VpcEndpointArgs{
+			Tags:            pulumi.StringMap{"Name": pulumi.String(endpointUniqueName)},
+			VpcId:           v.vpc.ID(),
+			ServiceName:     pulumi.String(vpcEndpointServiceName(region.Name, service)),
+			VpcEndpointType: pulumi.String("Gateway"),
+			RouteTableIds:   pulumi.ToStringArrayOutput(privateRouteTableIds),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(endpoints.Interface) == 0 {
+		return nil
+	}
+
+	securityGroup, err := v.vpcEndpointsSecurityGroup()
+	if err != nil {
+		return err
+	}
+
+	for _, service := range endpoints.Interface {
+		endpointUniqueName := vpcEndpointUniqueName(v.networking.Name, service)
+
+		_, err := ec2.NewVpcEndpoint(v.ctx, endpointUniqueName, &ec2.VpcEndpointArgs{
+			Tags:              pulumi.StringMap{"Name": pulumi.String(endpointUniqueName)},
+			VpcId:             v.vpc.ID(),
+			ServiceName:       pulumi.String(vpcEndpointServiceName(region.Name, service)),
+			VpcEndpointType:   pulumi.String("Interface"),
+			PrivateDnsEnabled: pulumi.Bool(true),
+			SubnetIds:         pulumi.ToStringArrayOutput(interfaceSubnetIds),
+			SecurityGroupIds:  pulumi.StringArray{securityGroup.ID()},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// vpcEndpointsSecurityGroup lets in https from every cidr block of the vpc,
+// the pods of the secondary cidr blocks and the ipv6 clients included.
+func (v *Networking) vpcEndpointsSecurityGroup() (*ec2.SecurityGroup, error) {
+	sgUniqueName := fmt.Sprintf("%s-vpce-sg", v.networking.Name)
+
+	cidrBlocks := pulumi.StringArray{v.vpc.CidrBlock}
+	for _, secondaryCidrBlock := range v.networking.SecondaryCidrBlocks {
+		cidrBlocks = append(cidrBlocks, pulumi.String(secondaryCidrBlock))
+	}
+
+	var ipv6CidrBlocks pulumi.StringArray
+	if v.networking.Ipv6 {
+		ipv6CidrBlocks = pulumi.StringArray{v.vpc.Ipv6CidrBlock}
+	}
+
+	return ec2.NewSecurityGroup(v.ctx, sgUniqueName, &ec2.SecurityGroupArgs{
+		Name:        pulumi.String(sgUniqueName),
+		Description: pulumi.String("HTTPS from the vpc to the interface vpc endpoints"),
+		Tags:        pulumi.StringMap{"Name": pulumi.String(sgUniqueName)},
+		VpcId:       v.vpc.ID(),
+		Ingress: ec2.SecurityGroupIngressArray{
+			ec2.SecurityGroupIngressArgs{
+				Protocol:       pulumi.String("tcp"),
+				FromPort:       pulumi.Int(443),
+				ToPort:         pulumi.Int(443),
+				CidrBlocks:     cidrBlocks,
+				Ipv6CidrBlocks: ipv6CidrBlocks,
+			},
+		},
+		Egress: ec2.SecurityGroupEgressArray{
+			ec2.SecurityGroupEgressArgs{
+				Protocol:   pulumi.String("-1"),
+				FromPort:   pulumi.Int(0),
+				ToPort:     pulumi.Int(0),
+				CidrBlocks: pulumi.StringArray{pulumi.String(types.PUBLIC_CIDR)},
+			},
+		},
+	})
+}
+
+func vpcEndpointServiceName(region, service string) string {
+	return fmt.Sprintf("com.amazonaws.%s.%s", region, service)
+}
+
+func vpcEndpointUniqueName(networkingName, service string) string {
+	return fmt.Sprintf("%s-vpce-%s", networkingName, strings.ReplaceAll(service, ".", "-"))
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func (v *Networking) existingVpc() error {
+	existing := v.networking.Existing
+
+	vpcID := existing.VpcID
+	if vpcID == "" {
+		vpcLookup, err := ec2.LookupVpc(v.ctx, &ec2.LookupVpcArgs{
+			Tags: existing.VpcTags,
+		})
+		if err != nil {
+			return fmt.Errorf("looking up the existing vpc by tags: %w", err)
+		}
+
+		vpcID = vpcLookup.Id
+	}
+
+	vpcUniqueName := fmt.Sprintf("%s-existing-vpc", v.networking.Name)
+	vpc, err := ec2.GetVpc(v.ctx, vpcUniqueName, pulumi.ID(vpcID), nil)
+	if err != nil {
+		return err
+	}
+
+	v.vpc = vpc
+	v.existingVpcID = vpcID
+
+	return nil
+}
+
+func (v *Networking) existingSubnets(d *types.InterServicesDependencies) error {
+	var sharedSubnetsBetweenResources = make(map[types.SubnetType][]*ec2.Subnet)
+	var subnetsByName = make(map[string]*ec2.Subnet, len(v.networking.Existing.Subnets))
+
+	for _, subnet := range v.networking.Existing.Subnets {
+		subnetID := subnet.ID
+		if subnetID == "" {
+			subnetLookup, err := ec2.LookupSubnet(v.ctx, &ec2.LookupSubnetArgs{
+				VpcId: pulumi.StringRef(v.existingVpcID),
+				Tags:  subnet.Tags,
+			})
+			if err != nil {
+				return fmt.Errorf("looking up the existing subnet %q by tags: %w", subnet.Name, err)
+			}
+
+			subnetID = subnetLookup.Id
+		}
+
+		subnetOutput, err := ec2.GetSubnet(v.ctx, subnet.Name, pulumi.ID(subnetID), nil)
+		if err != nil {
+			return err
+		}
+
+		subnetType := types.PRIVATE_SUBNET
+		switch {
+		case subnet.Public:
+			subnetType = types.PUBLIC_SUBNET
+		case subnet.Isolated:
+			subnetType = types.ISOLATED_SUBNET
+		}
+
+		sharedSubnetsBetweenResources[subnetType] = append(sharedSubnetsBetweenResources[subnetType], subnetOutput)
+		subnetsByName[subnet.Name] = subnetOutput
+	}
+
+	d.Subnets = sharedSubnetsBetweenResources
+	d.SubnetsByName = subnetsByName
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"pulumi-eks/internal/types"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	defaultFlowLogsTrafficType            = "ALL"
+	defaultFlowLogsMaxAggregationInterval = 600
+)
+
+// networkingFlowLogs captures the traffic of the vpc. The cloudwatch
+// destination comes with its own log group and the role the flow logs service
+// assumes to write to it, s3 buckets are referenced by arn.
+func (v *Networking) networkingFlowLogs() error {
+	flowLogs := v.networking.FlowLogs
+	if flowLogs == nil {
+		return nil
+	}
+
+	flowLogUniqueName := fmt.Sprintf("%s-flow-logs", v.networking.Name)
+
+	flowLogArgs := &ec2.FlowLogArgs{
+		Tags:                   pulumi.StringMap{"Name": pulumi.String(flowLogUniqueName)},
+		VpcId:                  v.vpc.ID(),
+		TrafficType:            pulumi.String(flowLogsTrafficType(flowLogs)),
+		MaxAggregationInterval: pulumi.Int(flowLogsMaxAggregationInterval(flowLogs)),
+	}
+
+	if flowLogs.LogFormat != "" {
+		flowLogArgs.LogFormat = pulumi.String(flowLogs.LogFormat)
+	}
+
+	switch flowLogs.Destination {
+	case types.FLOW_LOGS_DESTINATION_S3:
+		flowLogArgs.LogDestinationType = pulumi.String("s3")
+		flowLogArgs.LogDestination = pulumi.String(flowLogs.S3BucketArn)
+	default:
+		logGroup, err := cloudwatch.NewLogGroup(v.ctx, flowLogUniqueName, &cloudwatch.LogGroupArgs{
+			Name:            pulumi.Sprintf("/aws/vpc/%s/flow-logs", v.networking.Name),
+			RetentionInDays: pulumi.Int(flowLogs.RetentionInDays),
+			Tags:            pulumi.StringMap{"Name": pulumi.String(flowLogUniqueName)},
+		})
+		if err != nil {
+			return err
+		}
+
+		role, err := v.flowLogsRole(logGroup)
+		if err != nil {
+			return err
+		}
+
+		flowLogArgs.LogDestinationType = pulumi.String("cloud-watch-logs")
+		flowLogArgs.LogDestination = logGroup.Arn
+		flowLogArgs.IamRoleArn = role.Arn
+	}
+
+	_, err := ec2.NewFlowLog(v.ctx, flowLogUniqueName, flowLogArgs)
+
+	return err
+}
+
+func (v *Networking) flowLogsRole(logGroup *cloudwatch.LogGroup) (*iam.Role, error) {
+	assumeRolePolicyJSON, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Action": "sts:AssumeRole",
+				"Effect": "Allow",
+				"Principal": map[string]interface{}{
+					"Service": "vpc-flow-logs.amazonaws.com",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roleUniqueName := fmt.Sprintf("%s-flow-logs-role", v.networking.Name)
+
+	role, err := iam.NewRole(v.ctx, roleUniqueName, &iam.RoleArgs{
+		Name:             pulumi.String(roleUniqueName),
+		AssumeRolePolicy: pulumi.String(string(assumeRolePolicyJSON)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policyUniqueName := fmt.Sprintf("%s-flow-logs-policy", v.networking.Name)
+
+	policyJSON := logGroup.Arn.ApplyT(func(logGroupArn string) (string, error) {
+		policyJSON, err := json.Marshal(map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{
+				{
+					"Effect": "Allow",
+					"Action": []string{
+						"logs:CreateLogStream",
+						"logs:PutLogEvents",
+						"logs:DescribeLogGroups",
+						"logs:DescribeLogStreams",
+					},
+					"Resource": []string{logGroupArn, logGroupArn + ":*"},
+				},
+			},
+		})
+
+		return string(policyJSON), err
+	}).(pulumi.StringOutput)
+
+	_, err = iam.NewRolePolicy(v.ctx, policyUniqueName, &iam.RolePolicyArgs{
+		Role:   role.Name,
+		Policy: policyJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func flowLogsTrafficType(flowLogs *types.FlowLogs) string {
+	if flowLogs.TrafficType == "" {
+		return defaultFlowLogsTrafficType
+	}
+
+	return flowLogs.TrafficType
+}
+
+func flowLogsMaxAggregationInterval(flowLogs *types.FlowLogs) int {
+	if flowLogs.MaxAggregationInterval == 0 {
+		return defaultFlowLogsMaxAggregationInterval
+	}
+
+	return flowLogs.MaxAggregationInterval
+}
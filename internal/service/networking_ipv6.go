@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+	"pulumi-eks/pkg/cidr"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ipv6SubnetNewBits splits the amazon provided /56 vpc block into /64 subnets.
+const ipv6SubnetNewBits = 8
+
+// ipv6NetNum returns which /64 of the vpc block the subnet at index i gets,
+// its position in the subnets list unless pinned with ipv6NetNum.
+func ipv6NetNum(i int, subnet types.Subnets) int {
+	if subnet.Ipv6NetNum != nil {
+		return *subnet.Ipv6NetNum
+	}
+
+	return i
+}
+
+func subnetIpv6CidrBlock(vpcIpv6CidrBlock pulumi.StringOutput, netNum int) pulumi.StringPtrOutput {
+	return vpcIpv6CidrBlock.ApplyT(func(vpcCidr string) (*string, error) {
+		subnetCidr, err := cidr.SubnetString(vpcCidr, ipv6SubnetNewBits, netNum)
+		if err != nil {
+			return nil, err
+		}
+
+		return &subnetCidr, nil
+	}).(pulumi.StringPtrOutput)
+}
+
+func (v *Networking) networkingEgressOnlyInternetGateway() error {
+	if !v.networking.Ipv6 {
+		return nil
+	}
+
+	eigwUniqueName := fmt.Sprintf("%s-eigw", v.networking.Name)
+
+	egressOnlyGateway, err := ec2.NewEgressOnlyInternetGateway(v.ctx, eigwUniqueName, &ec2.EgressOnlyInternetGatewayArgs{
+		Tags:  pulumi.StringMap{"Name": pulumi.String(eigwUniqueName)},
+		VpcId: v.vpc.ID(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for name, config := range v.networkingConfigMap {
+		config.EgressOnlyGateway = egressOnlyGateway
+
+		v.networkingConfigMap[name] = config
+	}
+
+	return nil
+}
+
+// networkingIpv6Routes adds the ::/0 default route, through the internet
+// gateway for public subnets and the egress-only gateway for private ones.
+func (v *Networking) networkingIpv6Routes() error {
+	if !v.networking.Ipv6 {
+		return nil
+	}
+
+	for i, subnet := range v.networking.Subnets {
+		config, exists := v.networkingConfigMap[subnet.Name]
+		if !exists || config.Type == types.ISOLATED_SUBNET {
+			continue
+		}
+
+		routeArgs := &ec2.RouteArgs{
+			RouteTableId:             config.RouteTable.ID(),
+			DestinationIpv6CidrBlock: pulumi.String(types.PUBLIC_IPV6_CIDR),
+		}
+
+		if config.Type == types.PRIVATE_SUBNET {
+			routeArgs.EgressOnlyGatewayId = config.EgressOnlyGateway.ID()
+		} else {
+			routeArgs.GatewayId = config.InternetGateway.ID()
+		}
+
+		routeUniqueName := fmt.Sprintf("%v-route6-%d", subnet.Name, i)
+		if _, err := ec2.NewRoute(v.ctx, routeUniqueName, routeArgs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
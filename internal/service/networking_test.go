@@ -0,0 +1,429 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"pulumi-eks/internal/types"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type networkingMocks struct {
+	mu        sync.Mutex
+	resources map[string]resource.PropertyMap
+}
+
+func newNetworkingMocks() *networkingMocks {
+	return &networkingMocks{resources: make(map[string]resource.PropertyMap)}
+}
+
+func (m *networkingMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resources[args.Name] = args.Inputs
+
+	// the amazon provided block of a dual-stack vpc
+	outputs := args.Inputs
+	ipv6 := args.Inputs["assignGeneratedIpv6CidrBlock"]
+	if args.TypeToken == "aws:ec2/vpc:Vpc" && ipv6.IsBool() && ipv6.BoolValue() {
+		outputs = args.Inputs.Copy()
+		outputs["ipv6CidrBlock"] = resource.NewStringProperty("2600:1f18:abcd:1200::/56")
+	}
+
+	return args.Name + "-id", outputs, nil
+}
+
+func (m *networkingMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+// natSubnets returns, for every nat gateway registered, the id of the subnet
+// it was placed in.
+func (m *networkingMocks) natSubnets() map[string]string {
+	return m.inputsOf("-ngw", "subnetId")
+}
+
+// privateRoutes returns, for every route registered with a nat gateway, the
+// id of that nat gateway.
+func (m *networkingMocks) privateRoutes() map[string]string {
+	return m.inputsOf("priv-", "natGatewayId")
+}
+
+func (m *networkingMocks) inputsOf(namePart string, input resource.PropertyKey) map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make(map[string]string)
+	for name, inputs := range m.resources {
+		if strings.Contains(name, namePart) && inputs.HasValue(input) {
+			values[name] = inputs[input].StringValue()
+		}
+	}
+
+	return values
+}
+
+func testSubnet(name, cidr, az string, public bool) types.Subnets {
+	return types.Subnets{
+		Name:             name,
+		CidrBlock:        cidr,
+		AvailabilityZone: az,
+		PublicIpOnLaunch: public,
+	}
+}
+
+func runNetworking(t *testing.T, mode types.NatGatewayMode, subnets []types.Subnets) (*networkingMocks, error) {
+	t.Helper()
+
+	mocks := newNetworkingMocks()
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		networking := NewNetworking(ctx, types.Networking{
+			Name:      "test-vpc",
+			CidrBlock: "10.0.0.0/16",
+			Subnets:   subnets,
+			NatGateway: types.NatGateway{
+				Mode: mode,
+			},
+		})
+
+		return networking.Run(&types.InterServicesDependencies{})
+	}, pulumi.WithMocks("pulumi-eks", "test", mocks))
+
+	return mocks, err
+}
+
+func TestNetworkingNatGatewayPairedByAvailabilityZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		subnets  []types.Subnets
+		expected map[string]string
+	}{
+		{
+			name: "private listed before public",
+			subnets: []types.Subnets{
+				testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+				testSubnet("priv-1b", "10.0.1.0/24", "us-east-1b", false),
+				testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+				testSubnet("pub-1b", "10.0.3.0/24", "us-east-1b", true),
+			},
+			expected: map[string]string{
+				"test-vpc-ngw-0": "pub-1a-id",
+				"test-vpc-ngw-1": "pub-1b-id",
+			},
+		},
+		{
+			name: "public listed in reverse availability zone order",
+			subnets: []types.Subnets{
+				testSubnet("pub-1b", "10.0.3.0/24", "us-east-1b", true),
+				testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+				testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+				testSubnet("priv-1b", "10.0.1.0/24", "us-east-1b", false),
+			},
+			expected: map[string]string{
+				"test-vpc-ngw-2": "pub-1a-id",
+				"test-vpc-ngw-3": "pub-1b-id",
+			},
+		},
+		{
+			name: "more private than public subnets",
+			subnets: []types.Subnets{
+				testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+				testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+				testSubnet("priv-1a-2", "10.0.1.0/24", "us-east-1a", false),
+				testSubnet("priv-1a-3", "10.0.4.0/24", "us-east-1a", false),
+			},
+			expected: map[string]string{
+				"test-vpc-ngw-1": "pub-1a-id",
+				"test-vpc-ngw-2": "pub-1a-id",
+				"test-vpc-ngw-3": "pub-1a-id",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mocks, err := runNetworking(t, types.NAT_GATEWAY_PER_SUBNET, test.subnets)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertStringMap(t, "nat gateway subnets", test.expected, mocks.natSubnets())
+		})
+	}
+}
+
+func TestNetworkingPrivateSubnetWithoutPublicSubnetInAZ(t *testing.T) {
+	_, err := runNetworking(t, types.NAT_GATEWAY_PER_SUBNET, []types.Subnets{
+		testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+		testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+		testSubnet("priv-1c", "10.0.1.0/24", "us-east-1c", false),
+	})
+
+	if err == nil {
+		t.Fatal("expected an error for a private subnet without a public subnet in its availability zone")
+	}
+
+	if !strings.Contains(err.Error(), `"priv-1c"`) || !strings.Contains(err.Error(), "us-east-1c") {
+		t.Errorf("error does not name the subnet and availability zone: %v", err)
+	}
+}
+
+func TestNetworkingNatGatewayModes(t *testing.T) {
+	subnets := []types.Subnets{
+		testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+		testSubnet("pub-1b", "10.0.3.0/24", "us-east-1b", true),
+		testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+		testSubnet("priv-1a-2", "10.0.4.0/24", "us-east-1a", false),
+		testSubnet("priv-1b", "10.0.1.0/24", "us-east-1b", false),
+	}
+
+	tests := []struct {
+		mode           types.NatGatewayMode
+		expectedNats   map[string]string
+		expectedRoutes map[string]string
+	}{
+		{
+			mode: types.NAT_GATEWAY_PER_SUBNET,
+			expectedNats: map[string]string{
+				"test-vpc-ngw-2": "pub-1a-id",
+				"test-vpc-ngw-3": "pub-1a-id",
+				"test-vpc-ngw-4": "pub-1b-id",
+			},
+			expectedRoutes: map[string]string{
+				"priv-1a-route-2":   "test-vpc-ngw-2-id",
+				"priv-1a-2-route-3": "test-vpc-ngw-3-id",
+				"priv-1b-route-4":   "test-vpc-ngw-4-id",
+			},
+		},
+		{
+			mode: types.NAT_GATEWAY_PER_AZ,
+			expectedNats: map[string]string{
+				"test-vpc-ngw-us-east-1a": "pub-1a-id",
+				"test-vpc-ngw-us-east-1b": "pub-1b-id",
+			},
+			expectedRoutes: map[string]string{
+				"priv-1a-route-2":   "test-vpc-ngw-us-east-1a-id",
+				"priv-1a-2-route-3": "test-vpc-ngw-us-east-1a-id",
+				"priv-1b-route-4":   "test-vpc-ngw-us-east-1b-id",
+			},
+		},
+		{
+			mode: types.NAT_GATEWAY_SINGLE,
+			expectedNats: map[string]string{
+				"test-vpc-ngw": "pub-1a-id",
+			},
+			expectedRoutes: map[string]string{
+				"priv-1a-route-2":   "test-vpc-ngw-id",
+				"priv-1a-2-route-3": "test-vpc-ngw-id",
+				"priv-1b-route-4":   "test-vpc-ngw-id",
+			},
+		},
+		{
+			mode:           types.NAT_GATEWAY_NONE,
+			expectedNats:   map[string]string{},
+			expectedRoutes: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.mode), func(t *testing.T) {
+			mocks, err := runNetworking(t, test.mode, subnets)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertStringMap(t, "nat gateway subnets", test.expectedNats, mocks.natSubnets())
+			assertStringMap(t, "private routes", test.expectedRoutes, mocks.privateRoutes())
+		})
+	}
+}
+
+func assertStringMap(t *testing.T, what string, expected, actual map[string]string) {
+	t.Helper()
+
+	if len(actual) != len(expected) {
+		t.Fatalf("%s: expected %v, got %v", what, expected, actual)
+	}
+
+	for key, value := range expected {
+		if actual[key] != value {
+			t.Errorf("%s: %s expected %q, got %q", what, key, value, actual[key])
+		}
+	}
+}
+
+func TestNetworkingIsolatedSubnetHasNoDefaultRoute(t *testing.T) {
+	isolated := testSubnet("iso-1a", "10.0.5.0/24", "us-east-1a", false)
+	isolated.Isolated = true
+
+	mocks, err := runNetworking(t, types.NAT_GATEWAY_PER_SUBNET, []types.Subnets{
+		testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+		testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+		isolated,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStringMap(t, "nat gateway subnets", map[string]string{"test-vpc-ngw-1": "pub-1a-id"}, mocks.natSubnets())
+
+	if routes := mocks.inputsOf("iso-1a-route", "routeTableId"); len(routes) != 0 {
+		t.Errorf("expected no route for the isolated subnet, got %v", routes)
+	}
+
+	if associations := mocks.inputsOf("test-vpc-asc-2", "subnetId"); associations["test-vpc-asc-2"] != "iso-1a-id" {
+		t.Errorf("expected the isolated subnet to be associated to its own route table, got %v", associations)
+	}
+}
+
+func TestNetworkingPodSubnetsKeptApartFromPrivateSubnets(t *testing.T) {
+	pods := testSubnet("pods-1a", "100.64.0.0/18", "us-east-1a", false)
+	pods.Pods = true
+
+	mocks := newNetworkingMocks()
+	dependency := &types.InterServicesDependencies{}
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		networking := NewNetworking(ctx, types.Networking{
+			Name:                "test-vpc",
+			CidrBlock:           "10.0.0.0/16",
+			SecondaryCidrBlocks: []string{"100.64.0.0/16"},
+			Subnets: []types.Subnets{
+				testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+				testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+				pods,
+			},
+		})
+
+		return networking.Run(dependency)
+	}, pulumi.WithMocks("pulumi-eks", "test", mocks))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if associations := mocks.inputsOf("test-vpc-cidr-0", "cidrBlock"); associations["test-vpc-cidr-0"] != "100.64.0.0/16" {
+		t.Errorf("expected the secondary cidr block to be associated, got %v", associations)
+	}
+
+	if private := dependency.Subnets[types.PRIVATE_SUBNET]; len(private) != 1 {
+		t.Errorf("expected only the node subnet in the private subnets, got %d", len(private))
+	}
+
+	if _, found := dependency.PodSubnetsByAZ["us-east-1a"]; !found || len(dependency.PodSubnetsByAZ) != 1 {
+		t.Errorf("expected the pod subnet of us-east-1a, got %v", dependency.PodSubnetsByAZ)
+	}
+
+	if routes := mocks.inputsOf("pods-1a-route", "natGatewayId"); len(routes) != 1 {
+		t.Errorf("expected the pod subnet to reach the internet through a nat gateway, got %v", routes)
+	}
+}
+
+func TestNetworkingTransitGatewayRoutesOnPrivateRouteTables(t *testing.T) {
+	mocks := newNetworkingMocks()
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		networking := NewNetworking(ctx, types.Networking{
+			Name:      "test-vpc",
+			CidrBlock: "10.0.0.0/16",
+			Subnets: []types.Subnets{
+				testSubnet("pub-1a", "10.0.2.0/24", "us-east-1a", true),
+				testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+				testSubnet("priv-1b", "10.0.1.0/24", "us-east-1b", false),
+				testSubnet("pub-1b", "10.0.3.0/24", "us-east-1b", true),
+			},
+			Connectivity: types.Connectivity{
+				TransitGateway: &types.TransitGatewayAttachment{
+					ID:               "tgw-0123456789abcdef0",
+					RemoteCidrBlocks: []string{"10.100.0.0/16"},
+				},
+			},
+		})
+
+		return networking.Run(&types.InterServicesDependencies{})
+	}, pulumi.WithMocks("pulumi-eks", "test", mocks))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertStringMap(t, "transit gateway routes", map[string]string{
+		"priv-1a-tgw-10-100-0-0-16": "10.100.0.0/16",
+		"priv-1b-tgw-10-100-0-0-16": "10.100.0.0/16",
+	}, mocks.inputsOf("-tgw-10-100", "destinationCidrBlock"))
+}
+
+func TestNetworkAclRuleArgsIcmp(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     types.NetworkAclRule
+		protocol string
+	}{
+		{"icmp on ipv4", types.NetworkAclRule{Protocol: "icmp", CidrBlock: "0.0.0.0/0"}, "icmp"},
+		{"icmp on ipv6", types.NetworkAclRule{Protocol: "icmp", Ipv6CidrBlock: "::/0"}, "58"},
+		{"tcp on ipv6", types.NetworkAclRule{Protocol: "tcp", Ipv6CidrBlock: "::/0"}, "tcp"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := networkAclRuleArgs(test.rule)
+
+			if protocol := args.Protocol.(pulumi.String); string(protocol) != test.protocol {
+				t.Errorf("expected protocol %q, got %q", test.protocol, protocol)
+			}
+
+			if test.rule.Protocol == "icmp" && (args.IcmpType == nil || args.IcmpCode == nil) {
+				t.Errorf("expected every icmp type and code to be allowed")
+			}
+		})
+	}
+}
+
+func TestNetworkingVpcEndpointsReachableFromEveryCidrBlock(t *testing.T) {
+	mocks := newNetworkingMocks()
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		networking := NewNetworking(ctx, types.Networking{
+			Name:                "test-vpc",
+			CidrBlock:           "10.0.0.0/16",
+			SecondaryCidrBlocks: []string{"100.64.0.0/16"},
+			Ipv6:                true,
+			Subnets: []types.Subnets{
+				testSubnet("priv-1a", "10.0.0.0/24", "us-east-1a", false),
+			},
+			NatGateway:   types.NatGateway{Mode: types.NAT_GATEWAY_NONE},
+			VpcEndpoints: types.VpcEndpoints{Interface: []string{"ecr.api"}},
+		})
+
+		return networking.Run(&types.InterServicesDependencies{})
+	}, pulumi.WithMocks("pulumi-eks", "test", mocks))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mocks.mu.Lock()
+	defer mocks.mu.Unlock()
+
+	ingress := mocks.resources["test-vpc-vpce-sg"]["ingress"].ArrayValue()
+	if len(ingress) != 1 {
+		t.Fatalf("expected one ingress rule, got %v", ingress)
+	}
+
+	var cidrBlocks []string
+	for _, cidrBlock := range ingress[0].ObjectValue()["cidrBlocks"].ArrayValue() {
+		cidrBlocks = append(cidrBlocks, cidrBlock.StringValue())
+	}
+
+	if strings.Join(cidrBlocks, ",") != "10.0.0.0/16,100.64.0.0/16" {
+		t.Errorf("expected the primary and secondary cidr blocks, got %v", cidrBlocks)
+	}
+
+	ipv6CidrBlocks := ingress[0].ObjectValue()["ipv6CidrBlocks"]
+	if !ipv6CidrBlocks.IsArray() || len(ipv6CidrBlocks.ArrayValue()) != 1 ||
+		ipv6CidrBlocks.ArrayValue()[0].StringValue() != "2600:1f18:abcd:1200::/56" {
+		t.Errorf("expected the vpc ipv6 cidr block, got %v", ipv6CidrBlocks)
+	}
+}
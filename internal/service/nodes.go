@@ -51,6 +51,26 @@ func (c *NodeGroup) Run(dependency *types.InterServicesDependencies) error {
 	return nil
 }
 
+func (c *NodeGroup) Produces() []types.Dependency {
+	return []types.Dependency{types.NodeGroupsDependency}
+}
+
+func (c *NodeGroup) Consumes() []types.Dependency {
+	consumes := []types.Dependency{
+		types.SubnetsDependency,
+		types.ClusterDependency,
+		types.LaunchTemplatesDependency,
+		types.CustomNetworkingDependency,
+	}
+
+	// nodes roll last in upgrade mode, once the add-ons are upgraded
+	if c.cluster.Upgrade {
+		consumes = append(consumes, types.AddonsDependency)
+	}
+
+	return consumes
+}
+
 func (c *NodeGroup) createNodeGroup(dependency *types.InterServicesDependencies) error {
 	privateSubnetList, found := dependency.Subnets[types.PRIVATE_SUBNET]
 	if !found {
@@ -66,6 +86,14 @@ func (c *NodeGroup) createNodeGroup(dependency *types.InterServicesDependencies)
 		policyAttachmentDependsOn[i] = c.att[i]
 	}
 
+	// nodes must join after the ENIConfigs exist, nodes started before them
+	// never pick up the pod subnets
+	policyAttachmentDependsOn = append(policyAttachmentDependsOn, dependency.CustomNetworking...)
+
+	for _, addon := range dependency.Addons {
+		policyAttachmentDependsOn = append(policyAttachmentDependsOn, addon)
+	}
+
 	var nodeGroupOutputList types.NodeGroupsOutput
 
 	for nodeName, nodeGroupConfig := range dependency.LaunchTemplateOutputList {
@@ -78,7 +106,7 @@ func (c *NodeGroup) createNodeGroup(dependency *types.InterServicesDependencies)
 			Labels:        pulumi.ToStringMap(nodeGroupConfig.Node.NodeLabels),
 			LaunchTemplate: eks.NodeGroupLaunchTemplateArgs{
 				Id:      nodeGroupConfig.Lt.ID(),
-				Version: pulumi.String("$Latest"),
+				Version: launchTemplateVersion(nodeGroupConfig.Lt),
 			},
 			ScalingConfig: eks.NodeGroupScalingConfigArgs{
 				MinSize:     pulumi.Int(nodeGroupConfig.Node.ScalingConfig.MinSize),
@@ -96,9 +124,26 @@ func (c *NodeGroup) createNodeGroup(dependency *types.InterServicesDependencies)
 
 	dependency.NodeGroupsOutput = nodeGroupOutputList
 
+	if c.cluster.Upgrade {
+		releaseVersions := make([]interface{}, len(nodeGroupOutputList.NodeGroups))
+		for i, nodeGroup := range nodeGroupOutputList.NodeGroups {
+			releaseVersions[i] = nodeGroup.ReleaseVersion
+		}
+
+		reportUpgradePhase(c.ctx, 2, c.cluster.KubernetesVersion, releaseVersions...)
+	}
+
 	return nil
 }
 
+// launchTemplateVersion pins the latest version so a launch template change
+// shows up in the diff and rolls the nodes, $Latest never changing. The same
+// form is used in and out of upgrade mode, switching it would update every
+// node group again.
+func launchTemplateVersion(lt *ec2.LaunchTemplate) pulumi.StringInput {
+	return pulumi.Sprintf("%d", lt.LatestVersion)
+}
+
 func (c *NodeGroup) createNodeRole() error {
 	nodePolicyJSON, err := json.Marshal(map[string]interface{}{
 		"Statement": []map[string]interface{}{
@@ -148,5 +193,48 @@ func (c *NodeGroup) createNodeRole() error {
 	c.dependencies.nodeRole = nodeRole
 	c.dependencies.att = policyAttachmentList
 
+	if clusterIpFamily(c.cluster) == types.IP_FAMILY_IPV6 {
+		return c.createNodeIpv6CNIPolicy()
+	}
+
 	return nil
 }
+
+// createNodeIpv6CNIPolicy grants the vpc cni the ipv6 permissions missing
+// from AmazonEKS_CNI_Policy, as documented for ipv6 clusters.
+func (c *NodeGroup) createNodeIpv6CNIPolicy() error {
+	cniIpv6PolicyJSON, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect": "Allow",
+				"Action": []string{
+					"ec2:AssignIpv6Addresses",
+					"ec2:DescribeInstances",
+					"ec2:DescribeTags",
+					"ec2:DescribeNetworkInterfaces",
+					"ec2:DescribeInstanceTypes",
+				},
+				"Resource": "*",
+			},
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"ec2:CreateTags"},
+				"Resource": []string{"arn:aws:ec2:*:*:network-interface/*"},
+			},
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	cniIpv6PolicyName := fmt.Sprintf("%s-noderole-cni-ipv6", c.cluster.Name)
+	_, err = iam.NewRolePolicy(c.ctx, cniIpv6PolicyName, &iam.RolePolicyArgs{
+		Name:   pulumi.String(cniIpv6PolicyName),
+		Role:   c.dependencies.nodeRole.ID(),
+		Policy: pulumi.String(string(cniIpv6PolicyJSON)),
+	})
+
+	return err
+}
@@ -24,6 +24,23 @@ func (o *OIDC) Run(dependency *types.InterServicesDependencies) error {
 	return o.deployOIDCProvider(dependency)
 }
 
+func (o *OIDC) Produces() []types.Dependency {
+	return []types.Dependency{types.OIDCProviderDependency}
+}
+
+// Consumes drops the node groups in upgrade mode, the add-ons trusting the
+// provider running ahead of the node groups there.
+func (o *OIDC) Consumes() []types.Dependency {
+	if o.cluster.Upgrade {
+		return []types.Dependency{types.ClusterDependency}
+	}
+
+	return []types.Dependency{
+		types.ClusterDependency,
+		types.NodeGroupsDependency,
+	}
+}
+
 func (o *OIDC) deployOIDCProvider(dependency *types.InterServicesDependencies) error {
 	clusterNodesDependsOn := shared.RetrieveDependsOnList(dependency)
 
@@ -31,10 +48,16 @@ func (o *OIDC) deployOIDCProvider(dependency *types.InterServicesDependencies) e
 		Index(pulumi.Int(0)).Oidcs().
 		Index(pulumi.Int(0)).Issuer()
 
-	_, err := iam.NewOpenIdConnectProvider(o.ctx, "openid-connect-provider-eks", &iam.OpenIdConnectProviderArgs{
+	oidcProvider, err := iam.NewOpenIdConnectProvider(o.ctx, "openid-connect-provider-eks", &iam.OpenIdConnectProviderArgs{
 		Url:           oidc.Elem().ToStringOutput(),
 		ClientIdLists: pulumi.ToStringArray([]string{"sts.amazonaws.com"}),
 	}, pulumi.DependsOn(clusterNodesDependsOn))
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	dependency.OIDCProvider = oidcProvider
+
+	return nil
 }
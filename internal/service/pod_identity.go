@@ -61,6 +61,17 @@ func (p *PODIdentity) Run(dependency *types.InterServicesDependencies) error {
 	return nil
 }
 
+func (p *PODIdentity) Produces() []types.Dependency {
+	return nil
+}
+
+func (p *PODIdentity) Consumes() []types.Dependency {
+	return []types.Dependency{
+		types.ClusterDependency,
+		types.NodeGroupsDependency,
+	}
+}
+
 func (p *PODIdentity) validate() error {
 	if !p.identity.Deploy {
 		return types.ErrNotErrorServiceSkipped
@@ -244,13 +255,25 @@ func (p *PODIdentity) deployIdentityPodAgent(dependency *types.InterServicesDepe
 		return err
 	}
 
-	_, err = eks.NewAddon(p.ctx, "pod-identity-agent-addon", &eks.AddonArgs{
+	// without a version the installed one is kept, so an aws default bump does
+	// not upgrade the agent on an unrelated update
+	addonVersion, err := addonVersionInput(p.ctx, "eks-pod-identity-agent", p.cluster, p.identity.Version)
+	if err != nil {
+		return err
+	}
+
+	agent, err := eks.NewAddon(p.ctx, "pod-identity-agent-addon", &eks.AddonArgs{
 		AddonName:    pulumi.String("eks-pod-identity-agent"),
-		AddonVersion: pulumi.String("v1.3.4-eksbuild.1"),
+		AddonVersion: addonVersion,
 		ClusterName:  dependency.ClusterOutput.EKSCluster.Name,
 	}, pulumi.DependsOn(dependsOn), pulumi.Provider(provider))
+	if err != nil {
+		return err
+	}
+
+	p.ctx.Export("podIdentityAgentVersion", agent.AddonVersion)
 
-	return err
+	return nil
 }
 
 func (p *PODIdentity) createIdentityRoles(dependency *types.InterServicesDependencies) error {
@@ -4,6 +4,7 @@ import (
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/autoscaling"
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
 	yamlv2 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/yaml/v2"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
@@ -13,12 +14,70 @@ type SubnetType int
 const (
 	PRIVATE_SUBNET SubnetType = iota
 	PUBLIC_SUBNET
+	// ISOLATED_SUBNET has no route to the internet, neither nat nor igw
+	ISOLATED_SUBNET
 )
 
 const PUBLIC_CIDR = "0.0.0.0/0"
+const PUBLIC_IPV6_CIDR = "::/0"
+
+const (
+	IP_FAMILY_IPV4 = "ipv4"
+	IP_FAMILY_IPV6 = "ipv6"
+)
+
+const (
+	ENDPOINT_ACCESS_PUBLIC_AND_PRIVATE = "publicAndPrivate"
+	ENDPOINT_ACCESS_PRIVATE            = "private"
+)
+
+const (
+	AUTHENTICATION_MODE_API                = "API"
+	AUTHENTICATION_MODE_API_AND_CONFIG_MAP = "API_AND_CONFIG_MAP"
+)
+
+const (
+	ADDON_VERSION_LATEST = "latest"
+
+	ADDON_ROLE_MODE_POD_IDENTITY = "podIdentity"
+	ADDON_ROLE_MODE_IRSA         = "irsa"
+)
+
+const (
+	AMI_FAMILY_AL2023        = "al2023"
+	AMI_FAMILY_AL2023_NVIDIA = "al2023Nvidia"
+	AMI_FAMILY_AL2023_ARM64  = "al2023Arm64"
+	AMI_FAMILY_BOTTLEROCKET  = "bottlerocket"
+)
+
+type NatGatewayMode string
+
+const (
+	NAT_GATEWAY_PER_SUBNET NatGatewayMode = "perSubnet"
+	NAT_GATEWAY_PER_AZ     NatGatewayMode = "perAz"
+	NAT_GATEWAY_SINGLE     NatGatewayMode = "single"
+	NAT_GATEWAY_NONE       NatGatewayMode = "none"
+)
+
+type FlowLogsDestination string
+
+const (
+	FLOW_LOGS_DESTINATION_CLOUDWATCH FlowLogsDestination = "cloudWatch"
+	FLOW_LOGS_DESTINATION_S3         FlowLogsDestination = "s3"
+)
+
+const (
+	VPC_ID_PREFIX             = "vpc-"
+	SUBNET_ID_PREFIX          = "subnet-"
+	SECURITY_GROUP_ID_PREFIX  = "sg-"
+	TRANSIT_GATEWAY_ID_PREFIX = "tgw-"
+	AMI_ID_PREFIX             = "ami-"
+)
 
 type InterServicesDependencies struct {
-	Subnets map[SubnetType][]*ec2.Subnet
+	Subnets        map[SubnetType][]*ec2.Subnet
+	SubnetsByName  map[string]*ec2.Subnet
+	PodSubnetsByAZ map[string]*ec2.Subnet
 
 	AutoscalingGroup         *autoscaling.Group
 	LaunchTemplateOutputList map[string]NodeGroupMetadata
@@ -27,6 +86,12 @@ type InterServicesDependencies struct {
 
 	NodeGroupsOutput NodeGroupsOutput
 
+	OIDCProvider *iam.OpenIdConnectProvider
+
+	CustomNetworking []pulumi.Resource
+
+	Addons []*eks.Addon
+
 	PodIdentityAgent *yamlv2.ConfigGroup
 }
 type NodeGroupMetadata struct {
@@ -47,25 +112,199 @@ type Config struct {
 	Spec Spec `yaml:"spec"`
 }
 type Networking struct {
-	Name      string `yaml:"name"`
-	CidrBlock string `yaml:"cidrBlock"`
-	Subnets   []Subnets
+	Name                string              `yaml:"name"`
+	CidrBlock           string              `yaml:"cidrBlock"`
+	SecondaryCidrBlocks []string            `yaml:"secondaryCidrBlocks"`
+	Ipv6                bool                `yaml:"ipv6"`
+	Subnets             []Subnets           `yaml:"subnets"`
+	SubnetLayout        *SubnetLayout       `yaml:"subnetLayout"`
+	NatGateway          NatGateway          `yaml:"natGateway"`
+	VpcEndpoints        VpcEndpoints        `yaml:"vpcEndpoints"`
+	FlowLogs            *FlowLogs           `yaml:"flowLogs"`
+	Connectivity        Connectivity        `yaml:"connectivity"`
+	NetworkAcls         NetworkAcls         `yaml:"networkAcls"`
+	Existing            *ExistingNetworking `yaml:"existing"`
+}
+
+// SubnetLayout generates the subnets of every tier in every availability
+// zone, carving their cidr blocks out of the vpc cidr block.
+type SubnetLayout struct {
+	AvailabilityZones    []string    `yaml:"availabilityZones"`
+	MaxAvailabilityZones int         `yaml:"maxAvailabilityZones"`
+	Public               *SubnetTier `yaml:"public"`
+	Private              *SubnetTier `yaml:"private"`
+	Isolated             *SubnetTier `yaml:"isolated"`
+	Pods                 *SubnetTier `yaml:"pods"`
+}
+
+type SubnetTier struct {
+	PrefixLength int                    `yaml:"prefixLength"`
+	Tags         map[string]interface{} `yaml:"tags"`
+}
+
+// VpcEndpoints lists aws services reached privately from the vpc, by their
+// short service name (s3, ecr.api, sts...).
+type VpcEndpoints struct {
+	Gateway   []string `yaml:"gateway"`
+	Interface []string `yaml:"interface"`
+}
+
+// FlowLogs captures the ip traffic of the whole vpc, to a cloudwatch log group
+// created by the stack or to an existing s3 bucket.
+type FlowLogs struct {
+	Destination            FlowLogsDestination `yaml:"destination"`
+	TrafficType            string              `yaml:"trafficType"`
+	MaxAggregationInterval int                 `yaml:"maxAggregationInterval"`
+	LogFormat              string              `yaml:"logFormat"`
+	RetentionInDays        int                 `yaml:"retentionInDays"`
+	S3BucketArn            string              `yaml:"s3BucketArn"`
+}
+
+// Connectivity links the vpc to other networks, every remote cidr block being
+// routed from the private route tables.
+type Connectivity struct {
+	TransitGateway *TransitGatewayAttachment `yaml:"transitGateway"`
+	Peerings       []VpcPeering              `yaml:"peerings"`
+}
+
+type TransitGatewayAttachment struct {
+	ID               string   `yaml:"id"`
+	RemoteCidrBlocks []string `yaml:"remoteCidrBlocks"`
+}
+
+type VpcPeering struct {
+	Name             string   `yaml:"name"`
+	PeerVpcID        string   `yaml:"peerVpcId"`
+	PeerOwnerID      string   `yaml:"peerOwnerId"`
+	PeerRegion       string   `yaml:"peerRegion"`
+	RemoteCidrBlocks []string `yaml:"remoteCidrBlocks"`
+}
+
+// NetworkAcls replaces the default network acl of the subnets of a tier, pod
+// subnets being part of the private tier.
+type NetworkAcls struct {
+	Public   *NetworkAcl `yaml:"public"`
+	Private  *NetworkAcl `yaml:"private"`
+	Isolated *NetworkAcl `yaml:"isolated"`
+}
+
+type NetworkAcl struct {
+	Ingress []NetworkAclRule `yaml:"ingress"`
+	Egress  []NetworkAclRule `yaml:"egress"`
+}
+
+// NetworkAclRule is evaluated in ruleNumber order, the first matching rule
+// allowing or denying the traffic.
+type NetworkAclRule struct {
+	RuleNumber    int    `yaml:"ruleNumber"`
+	Protocol      string `yaml:"protocol"`
+	Action        string `yaml:"action"`
+	CidrBlock     string `yaml:"cidrBlock"`
+	Ipv6CidrBlock string `yaml:"ipv6CidrBlock"`
+	FromPort      int    `yaml:"fromPort"`
+	ToPort        int    `yaml:"toPort"`
+}
+
+type NatGateway struct {
+	Mode NatGatewayMode `yaml:"mode"`
+}
+
+// ExistingNetworking references a vpc and subnets owned outside of this
+// stack. When set, no networking resource is created.
+type ExistingNetworking struct {
+	VpcID   string            `yaml:"vpcId"`
+	VpcTags map[string]string `yaml:"vpcTags"`
+	Subnets []ExistingSubnet  `yaml:"subnets"`
+}
+
+type ExistingSubnet struct {
+	Name     string            `yaml:"name"`
+	ID       string            `yaml:"id"`
+	Tags     map[string]string `yaml:"tags"`
+	Public   bool              `yaml:"public"`
+	Isolated bool              `yaml:"isolated"`
 }
 type Subnets struct {
 	Name             string                 `yaml:"name"`
 	CidrBlock        string                 `yaml:"cidrBlock"`
 	PublicIpOnLaunch bool                   `yaml:"publicIpOnLaunch"`
+	Isolated         bool                   `yaml:"isolated"`
+	Pods             bool                   `yaml:"pods"`
 	AvailabilityZone string                 `yaml:"availabilityZone"`
+	Ipv6NetNum       *int                   `yaml:"ipv6NetNum"`
 	Tags             map[string]interface{} `yaml:"tags"`
 }
 type Cluster struct {
-	Name              string   `yaml:"name"`
-	Environment       string   `yaml:"environment"`
-	Region            string   `yaml:"region"`
-	KubernetesVersion string   `yaml:"kubernetesVersion"`
-	VpcID             string   `yaml:"vpcId"`
-	Subnets           []string `yaml:"subnets"`
-	SecurityGroups    []string `yaml:"securityGroups"`
+	Name              string          `yaml:"name"`
+	Environment       string          `yaml:"environment"`
+	Region            string          `yaml:"region"`
+	KubernetesVersion string          `yaml:"kubernetesVersion"`
+	IpFamily          string          `yaml:"ipFamily"`
+	VpcID             string          `yaml:"vpcId"`
+	Subnets           []string        `yaml:"subnets"`
+	SecurityGroups    []string        `yaml:"securityGroups"`
+	ApiAccess         ApiAccess       `yaml:"apiAccess"`
+	EndpointAccess    string          `yaml:"endpointAccess"`
+	Bastion           *Bastion        `yaml:"bastion"`
+	Access            *ClusterAccess  `yaml:"access"`
+	Encryption        *Encryption     `yaml:"encryption"`
+	Logging           *ClusterLogging `yaml:"logging"`
+	Upgrade           bool            `yaml:"upgrade"`
+}
+
+// ClusterLogging sends the control plane logs of the listed types to the
+// /aws/eks/<name>/cluster log group, created by the stack so it is deleted
+// with it.
+type ClusterLogging struct {
+	Types           []string `yaml:"types"`
+	RetentionInDays int      `yaml:"retentionInDays"`
+	KmsKeyArn       string   `yaml:"kmsKeyArn"`
+}
+
+// Encryption envelope encrypts the kubernetes secrets with a kms key, the one
+// referenced by kmsKeyArn or a customer managed key created with rotation on.
+type Encryption struct {
+	KmsKeyArn            string `yaml:"kmsKeyArn"`
+	DeletionWindowInDays int    `yaml:"deletionWindowInDays"`
+}
+
+// ClusterAccess grants iam principals access to the cluster through eks
+// access entries instead of the aws-auth config map.
+type ClusterAccess struct {
+	AuthenticationMode string        `yaml:"authenticationMode"`
+	Entries            []AccessEntry `yaml:"entries"`
+}
+
+type AccessEntry struct {
+	PrincipalArn     string         `yaml:"principalArn"`
+	KubernetesGroups []string       `yaml:"kubernetesGroups"`
+	Policies         []AccessPolicy `yaml:"policies"`
+}
+
+// AccessPolicy is an eks access policy, by name (AmazonEKSViewPolicy) or arn,
+// scoped to the namespaces listed or to the whole cluster when none is.
+type AccessPolicy struct {
+	Policy     string   `yaml:"policy"`
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// Bastion is an instance with no inbound access, reached through SSM Session
+// Manager to tunnel to a private api endpoint. With tunnelPort set, the
+// kubernetes providers talk to the api through localhost:tunnelPort.
+type Bastion struct {
+	InstanceType string `yaml:"instanceType"`
+	Subnet       string `yaml:"subnet"`
+	TunnelPort   int    `yaml:"tunnelPort"`
+	Ami          string `yaml:"ami"`
+}
+
+// ApiAccess restricts who reaches the kubernetes api. The allowed cidrs and
+// security groups open 443 on the cluster security group, the public access
+// cidrs filter the public endpoint itself.
+type ApiAccess struct {
+	AllowedCidrs          []string `yaml:"allowedCidrs"`
+	AllowedSecurityGroups []string `yaml:"allowedSecurityGroups"`
+	PublicAccessCidrs     []string `yaml:"publicAccessCidrs"`
 }
 type ScalingConfig struct {
 	MinSize     int `yaml:"minSize"`
@@ -73,11 +312,13 @@ type ScalingConfig struct {
 	MaxSize     int `yaml:"maxSize"`
 }
 type NodeGroups struct {
-	Name          string            `yaml:"name"`
-	ScalingConfig ScalingConfig     `yaml:"scalingConfig"`
-	InstanceType  string            `yaml:"instanceType"`
-	NodeLabels    map[string]string `yaml:"nodeLabels"`
-	ImageId       string            `yaml:"imageId"`
+	Name              string            `yaml:"name"`
+	ScalingConfig     ScalingConfig     `yaml:"scalingConfig"`
+	InstanceType      string            `yaml:"instanceType"`
+	NodeLabels        map[string]string `yaml:"nodeLabels"`
+	ImageId           string            `yaml:"imageId"`
+	AmiFamily         string            `yaml:"amiFamily"`
+	AmiReleaseVersion string            `yaml:"amiReleaseVersion"`
 }
 type Components struct {
 	Name             string                 `yaml:"name"`
@@ -87,7 +328,7 @@ type Components struct {
 	Namespace        string                 `yaml:"namespace"`
 	SetValues        map[string]interface{} `yaml:"setValues"`
 	CreateNamespace  bool                   `yaml:"createNamespace,omitempty"`
-	SkipCirds        bool                   `yaml:"skipCirds"`
+	SkipCrds         bool                   `yaml:"skipCrds"`
 	WithOIDCProvider *WithOIDCProvider      `yaml:"withOidcProvider"`
 }
 
@@ -107,12 +348,33 @@ type ServiceAccount struct {
 	Name string `yaml:"name"`
 }
 
+// Addon is an eks managed add-on. With no version, the default version for
+// the cluster kubernetes version is installed, latest picks the most recent
+// compatible one.
+type Addon struct {
+	Name                string                   `yaml:"name"`
+	Version             string                   `yaml:"version"`
+	ConfigurationValues map[string]interface{}   `yaml:"configurationValues"`
+	ResolveConflicts    string                   `yaml:"resolveConflicts"`
+	ServiceAccountRole  *AddonServiceAccountRole `yaml:"serviceAccountRole"`
+}
+
+// AddonServiceAccountRole is the iam role of the add-on service account,
+// granted through a pod identity association or IRSA.
+type AddonServiceAccountRole struct {
+	Mode           string   `yaml:"mode"`
+	ServiceAccount string   `yaml:"serviceAccount"`
+	Namespace      string   `yaml:"namespace"`
+	AwsPolicies    []string `yaml:"awsPolicies"`
+}
+
 type HelmChartsComponentes struct {
 	Components []Components `yaml:"components"`
 }
 
 type IdentityPodAgent struct {
 	Deploy     bool       `yaml:"deploy"`
+	Version    string     `yaml:"version"`
 	Identities Identities `yaml:"identities"`
 }
 
@@ -138,4 +400,5 @@ type Spec struct {
 	NodeGroups            []NodeGroups          `yaml:"nodeGroups"`
 	HelmChartsComponentes HelmChartsComponentes `yaml:"helmChartsComponentes"`
 	IdentityPodAgent      IdentityPodAgent      `yaml:"identityPodAgent"`
+	Addons                []Addon               `yaml:"addons"`
 }
@@ -0,0 +1,15 @@
+package types
+
+// Dependency names a piece of InterServicesDependencies that a service either
+// fills in (produces) or reads from (consumes).
+type Dependency string
+
+const (
+	SubnetsDependency          Dependency = "subnets"
+	ClusterDependency          Dependency = "cluster"
+	LaunchTemplatesDependency  Dependency = "launchTemplates"
+	NodeGroupsDependency       Dependency = "nodeGroups"
+	OIDCProviderDependency     Dependency = "oidcProvider"
+	CustomNetworkingDependency Dependency = "customNetworking"
+	AddonsDependency           Dependency = "addons"
+)
@@ -4,3 +4,6 @@ import "errors"
 
 var ErrNotErrorServiceSkipped = errors.New("service skipped")
 var ErrNotErrorDisabledOIDCProvider = errors.New("disabled oidc provider")
+var ErrDependencyCycle = errors.New("dependency cycle between services")
+var ErrMissingProducer = errors.New("no service produces the dependency")
+var ErrDependencyUnavailable = errors.New("dependency was not produced")
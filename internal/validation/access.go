@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"pulumi-eks/internal/types"
+	"regexp"
+	"strings"
+)
+
+// iamPrincipalArnPattern matches role and user arns of every partition, such
+// as aws-cn and aws-us-gov.
+var iamPrincipalArnPattern = regexp.MustCompile(`^arn:aws(-[a-z]+)*:iam::[0-9]{12}:(role|user)/.+$`)
+
+func (v *validator) clusterAccess(path string, access types.ClusterAccess) {
+	switch access.AuthenticationMode {
+	case "", types.AUTHENTICATION_MODE_API, types.AUTHENTICATION_MODE_API_AND_CONFIG_MAP:
+	default:
+		v.addf(field(path, "authenticationMode"), "unknown authentication mode %q, expected %s or %s",
+			access.AuthenticationMode, types.AUTHENTICATION_MODE_API, types.AUTHENTICATION_MODE_API_AND_CONFIG_MAP,
+		)
+	}
+
+	entriesPath := field(path, "entries")
+	principals := make(map[string]int, len(access.Entries))
+
+	for i, entry := range access.Entries {
+		entryPath := index(entriesPath, i)
+		principalPath := field(entryPath, "principalArn")
+
+		if v.required(principalPath, entry.PrincipalArn) {
+			if !iamPrincipalArnPattern.MatchString(entry.PrincipalArn) {
+				v.addf(principalPath, "%q is not an iam role or user arn", entry.PrincipalArn)
+			}
+
+			if previous, exists := principals[entry.PrincipalArn]; exists {
+				v.addf(principalPath, "duplicated principal %q, already granted by %s", entry.PrincipalArn, index(entriesPath, previous))
+			} else {
+				principals[entry.PrincipalArn] = i
+			}
+		}
+
+		if len(entry.Policies) == 0 && len(entry.KubernetesGroups) == 0 {
+			v.addf(entryPath, "grants nothing, set policies or kubernetesGroups")
+		}
+
+		policiesPath := field(entryPath, "policies")
+		policies := make(map[string]int, len(entry.Policies))
+
+		for j, policy := range entry.Policies {
+			policyPath := field(index(policiesPath, j), "policy")
+
+			if !v.required(policyPath, policy.Policy) {
+				continue
+			}
+
+			name := policy.Policy[strings.LastIndex(policy.Policy, "/")+1:]
+			if previous, exists := policies[name]; exists {
+				v.addf(policyPath, "duplicated policy %q, already associated by %s", name, index(policiesPath, previous))
+				continue
+			}
+			policies[name] = j
+
+			for k, namespace := range policy.Namespaces {
+				v.required(index(field(index(policiesPath, j), "namespaces"), k), namespace)
+			}
+		}
+	}
+}
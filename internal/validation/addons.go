@@ -0,0 +1,81 @@
+package validation
+
+import (
+	"pulumi-eks/internal/types"
+	"strings"
+)
+
+const podIdentityAgentAddon = "eks-pod-identity-agent"
+
+var addonResolveConflicts = map[string]bool{"OVERWRITE": true, "PRESERVE": true, "NONE": true}
+
+func (v *validator) addons(path string, addons []types.Addon, spec types.Spec) {
+	names := make(map[string]int, len(addons))
+	podIdentityAgent := spec.IdentityPodAgent.Deploy
+
+	for _, addon := range addons {
+		if addon.Name == podIdentityAgentAddon {
+			podIdentityAgent = true
+		}
+	}
+
+	for i, addon := range addons {
+		addonPath := index(path, i)
+
+		if v.required(field(addonPath, "name"), addon.Name) {
+			if previous, exists := names[addon.Name]; exists {
+				v.addf(field(addonPath, "name"), "duplicated addon %q, already declared by %s", addon.Name, index(path, previous))
+			} else {
+				names[addon.Name] = i
+			}
+		}
+
+		switch {
+		case addon.Name == podIdentityAgentAddon && spec.IdentityPodAgent.Deploy:
+			v.addf(field(addonPath, "name"), "%s is already installed by spec.identityPodAgent", podIdentityAgentAddon)
+		case addon.Name == "vpc-cni" && hasPodSubnets(spec.Networking):
+			v.addf(field(addonPath, "name"), "vpc-cni is already configured for the pod subnets of spec.networking")
+		}
+
+		if addon.Version != "" && addon.Version != types.ADDON_VERSION_LATEST && !strings.HasPrefix(addon.Version, "v") {
+			v.addf(field(addonPath, "version"), "%q must be a version such as v1.19.2-eksbuild.1 or %s", addon.Version, types.ADDON_VERSION_LATEST)
+		}
+
+		if addon.ResolveConflicts != "" && !addonResolveConflicts[addon.ResolveConflicts] {
+			v.addf(field(addonPath, "resolveConflicts"), "unknown value %q, expected OVERWRITE, PRESERVE or NONE", addon.ResolveConflicts)
+		}
+
+		if addon.ServiceAccountRole != nil {
+			v.addonServiceAccountRole(field(addonPath, "serviceAccountRole"), *addon.ServiceAccountRole, podIdentityAgent)
+		}
+	}
+}
+
+func (v *validator) addonServiceAccountRole(path string, role types.AddonServiceAccountRole, podIdentityAgent bool) {
+	switch role.Mode {
+	case "", types.ADDON_ROLE_MODE_POD_IDENTITY:
+		if !podIdentityAgent {
+			v.addf(field(path, "mode"), "pod identity requires spec.identityPodAgent.deploy or the %s addon", podIdentityAgentAddon)
+		}
+
+		if role.Namespace != "" {
+			v.addf(field(path, "namespace"), "only used with mode %s, pod identity uses the addon namespace", types.ADDON_ROLE_MODE_IRSA)
+		}
+	case types.ADDON_ROLE_MODE_IRSA:
+	default:
+		v.addf(field(path, "mode"), "unknown mode %q, expected %s or %s", role.Mode, types.ADDON_ROLE_MODE_POD_IDENTITY, types.ADDON_ROLE_MODE_IRSA)
+	}
+
+	v.required(field(path, "serviceAccount"), role.ServiceAccount)
+
+	policiesPath := field(path, "awsPolicies")
+	if len(role.AwsPolicies) == 0 {
+		v.addf(policiesPath, "at least one policy is required")
+	}
+
+	for i, policy := range role.AwsPolicies {
+		if !strings.HasPrefix(policy, "arn:") {
+			v.addf(index(policiesPath, i), "%q is not a policy arn", policy)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package validation
+
+import (
+	"pulumi-eks/internal/types"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var kubernetesVersionPattern = regexp.MustCompile(`^1\.[0-9]+$`)
+
+// kmsKeyArnPattern matches key arns of every partition, aliases excluded.
+var kmsKeyArnPattern = regexp.MustCompile(`^arn:aws(-[a-z]+)*:kms:[a-z0-9-]+:[0-9]{12}:key/[A-Za-z0-9-]+$`)
+
+func (v *validator) cluster(path string, cluster types.Cluster, networking types.Networking) {
+	v.required(field(path, "name"), cluster.Name)
+	v.required(field(path, "region"), cluster.Region)
+
+	if v.required(field(path, "kubernetesVersion"), cluster.KubernetesVersion) &&
+		!kubernetesVersionPattern.MatchString(cluster.KubernetesVersion) {
+		v.addf(field(path, "kubernetesVersion"), "%q must be in the major.minor format, e.g. 1.31", cluster.KubernetesVersion)
+	}
+
+	switch cluster.IpFamily {
+	case "", types.IP_FAMILY_IPV4:
+	case types.IP_FAMILY_IPV6:
+		if networking.Existing == nil && !networking.Ipv6 {
+			v.addf(field(path, "ipFamily"), "ipv6 clusters require spec.networking.ipv6 to be enabled")
+		}
+
+		if hasPodSubnets(networking) {
+			v.addf(field(path, "ipFamily"), "ipv6 clusters do not support pod subnets, pods already get ipv6 addresses from the node subnets")
+		}
+	default:
+		v.addf(field(path, "ipFamily"), "unknown ip family %q, expected %s or %s", cluster.IpFamily, types.IP_FAMILY_IPV4, types.IP_FAMILY_IPV6)
+	}
+
+	byID := strings.HasPrefix(cluster.VpcID, types.VPC_ID_PREFIX)
+	switch {
+	case cluster.VpcID == "" || cluster.VpcID == networking.Name:
+	case !byID:
+		v.addf(field(path, "vpcId"), "%q must be either a vpc id or the networking name %q", cluster.VpcID, networking.Name)
+	case networking.Existing != nil && networking.Existing.VpcID != "" && networking.Existing.VpcID != cluster.VpcID:
+		v.addf(field(path, "vpcId"), "%q does not match the existing networking vpc %q", cluster.VpcID, networking.Existing.VpcID)
+	}
+
+	v.clusterSubnets(field(path, "subnets"), cluster, networking, byID && networking.Existing == nil)
+
+	for i, securityGroup := range cluster.SecurityGroups {
+		if !strings.HasPrefix(securityGroup, types.SECURITY_GROUP_ID_PREFIX) {
+			v.addf(index(field(path, "securityGroups"), i), "%q is not a security group id", securityGroup)
+		}
+	}
+
+	v.apiAccess(field(path, "apiAccess"), cluster.ApiAccess)
+
+	switch cluster.EndpointAccess {
+	case "", types.ENDPOINT_ACCESS_PUBLIC_AND_PRIVATE:
+	case types.ENDPOINT_ACCESS_PRIVATE:
+		if len(cluster.ApiAccess.PublicAccessCidrs) > 0 {
+			v.addf(field(field(path, "apiAccess"), "publicAccessCidrs"), "there is no public endpoint with endpointAccess %s", types.ENDPOINT_ACCESS_PRIVATE)
+		}
+	default:
+		v.addf(field(path, "endpointAccess"), "unknown endpoint access %q, expected %s or %s",
+			cluster.EndpointAccess, types.ENDPOINT_ACCESS_PUBLIC_AND_PRIVATE, types.ENDPOINT_ACCESS_PRIVATE,
+		)
+	}
+
+	if cluster.Bastion != nil {
+		v.bastion(field(path, "bastion"), *cluster.Bastion, networking)
+	}
+
+	if cluster.Access != nil {
+		v.clusterAccess(field(path, "access"), *cluster.Access)
+	}
+
+	if cluster.Encryption != nil {
+		v.encryption(field(path, "encryption"), *cluster.Encryption)
+	}
+
+	if cluster.Logging != nil {
+		v.clusterLogging(field(path, "logging"), *cluster.Logging)
+	}
+}
+
+func (v *validator) encryption(path string, encryption types.Encryption) {
+	if encryption.KmsKeyArn != "" {
+		if !kmsKeyArnPattern.MatchString(encryption.KmsKeyArn) {
+			v.addf(field(path, "kmsKeyArn"), "%q is not a kms key arn, expected arn:<partition>:kms:<region>:<account>:key/<key id>", encryption.KmsKeyArn)
+		}
+
+		if encryption.DeletionWindowInDays != 0 {
+			v.addf(field(path, "deletionWindowInDays"), "only used for the key created when kmsKeyArn is empty")
+		}
+		return
+	}
+
+	if encryption.DeletionWindowInDays != 0 && (encryption.DeletionWindowInDays < 7 || encryption.DeletionWindowInDays > 30) {
+		v.addf(field(path, "deletionWindowInDays"), "must be between 7 and 30 days, got %d", encryption.DeletionWindowInDays)
+	}
+}
+
+var clusterLogTypes = map[string]bool{"api": true, "audit": true, "authenticator": true, "controllerManager": true, "scheduler": true}
+
+func (v *validator) clusterLogging(path string, logging types.ClusterLogging) {
+	typesPath := field(path, "types")
+	if len(logging.Types) == 0 {
+		v.addf(typesPath, "at least one log type is required")
+	}
+
+	seen := make(map[string]int, len(logging.Types))
+	for i, logType := range logging.Types {
+		if !clusterLogTypes[logType] {
+			v.addf(index(typesPath, i), "unknown log type %q, expected api, audit, authenticator, controllerManager or scheduler", logType)
+			continue
+		}
+
+		if previous, exists := seen[logType]; exists {
+			v.addf(index(typesPath, i), "duplicated log type %q, already listed at %s", logType, index(typesPath, previous))
+			continue
+		}
+		seen[logType] = i
+	}
+
+	if logging.RetentionInDays != 0 && !slices.Contains(cloudwatchRetentionInDays, logging.RetentionInDays) {
+		v.addf(field(path, "retentionInDays"), "%d is not a cloudwatch retention, expected one of %v", logging.RetentionInDays, cloudwatchRetentionInDays)
+	}
+
+	if logging.KmsKeyArn != "" && !kmsKeyArnPattern.MatchString(logging.KmsKeyArn) {
+		v.addf(field(path, "kmsKeyArn"), "%q is not a kms key arn, expected arn:<partition>:kms:<region>:<account>:key/<key id>", logging.KmsKeyArn)
+	}
+}
+
+func (v *validator) bastion(path string, bastion types.Bastion, networking types.Networking) {
+	if bastion.TunnelPort < 0 || bastion.TunnelPort > 65535 {
+		v.addf(field(path, "tunnelPort"), "must be a port between 1 and 65535, got %d", bastion.TunnelPort)
+	}
+
+	if bastion.Ami != "" && !strings.HasPrefix(bastion.Ami, types.AMI_ID_PREFIX) {
+		v.addf(field(path, "ami"), "%q is not an ami id", bastion.Ami)
+	}
+
+	switch {
+	case bastion.Subnet == "":
+		if !hasPrivateSubnet(networking) {
+			v.addf(field(path, "subnet"), "no subnet set and no private subnet found in spec.networking to default to")
+		}
+	case subnetNames(networking)[bastion.Subnet], strings.HasPrefix(bastion.Subnet, types.SUBNET_ID_PREFIX):
+	default:
+		v.addf(field(path, "subnet"), "subnet %q is not declared in spec.networking", bastion.Subnet)
+	}
+
+	if missing := bastionMissingEndpoints(bastion, networking); len(missing) > 0 {
+		v.addf(path, "session manager cannot reach the bastion with natGateway.mode %s, add the %s interface vpc endpoints",
+			types.NAT_GATEWAY_NONE, strings.Join(missing, ", "),
+		)
+	}
+}
+
+// bastionSsmEndpoints are the services the ssm agent of the bastion talks to.
+var bastionSsmEndpoints = []string{"ssm", "ssmmessages", "ec2messages"}
+
+// bastionMissingEndpoints returns the ssm endpoints a bastion with no internet
+// egress lacks. A bastion placed in a public subnet keeps its egress.
+func bastionMissingEndpoints(bastion types.Bastion, networking types.Networking) []string {
+	if networking.Existing != nil || networking.NatGateway.Mode != types.NAT_GATEWAY_NONE {
+		return nil
+	}
+
+	for _, subnet := range networking.Subnets {
+		if subnet.Name == bastion.Subnet && subnet.PublicIpOnLaunch {
+			return nil
+		}
+	}
+
+	var missing []string
+	for _, service := range bastionSsmEndpoints {
+		if !slices.Contains(networking.VpcEndpoints.Interface, service) {
+			missing = append(missing, service)
+		}
+	}
+
+	return missing
+}
+
+func (v *validator) apiAccess(path string, apiAccess types.ApiAccess) {
+	cidrs := func(path string, cidrBlocks []string) {
+		seen := make(map[string]int, len(cidrBlocks))
+
+		for i, cidrBlock := range cidrBlocks {
+			if previous, exists := seen[cidrBlock]; exists {
+				v.addf(index(path, i), "duplicated cidr block %q, already listed at %s", cidrBlock, index(path, previous))
+				continue
+			}
+			seen[cidrBlock] = i
+
+			v.prefix(index(path, i), cidrBlock)
+		}
+	}
+
+	cidrs(field(path, "allowedCidrs"), apiAccess.AllowedCidrs)
+	cidrs(field(path, "publicAccessCidrs"), apiAccess.PublicAccessCidrs)
+
+	seen := make(map[string]int, len(apiAccess.AllowedSecurityGroups))
+	securityGroupsPath := field(path, "allowedSecurityGroups")
+
+	for i, securityGroup := range apiAccess.AllowedSecurityGroups {
+		if !strings.HasPrefix(securityGroup, types.SECURITY_GROUP_ID_PREFIX) {
+			v.addf(index(securityGroupsPath, i), "%q is not a security group id", securityGroup)
+			continue
+		}
+
+		if previous, exists := seen[securityGroup]; exists {
+			v.addf(index(securityGroupsPath, i), "duplicated security group %q, already listed at %s", securityGroup, index(securityGroupsPath, previous))
+			continue
+		}
+		seen[securityGroup] = i
+	}
+}
+
+func (v *validator) clusterSubnets(path string, cluster types.Cluster, networking types.Networking, byVpcID bool) {
+	if len(cluster.Subnets) == 0 {
+		if !hasPublicSubnet(networking) {
+			v.addf(path, "no subnets listed and no public subnet found in spec.networking to default to")
+		}
+		return
+	}
+
+	names := subnetNames(networking)
+
+	for i, subnet := range cluster.Subnets {
+		switch {
+		case byVpcID && strings.HasPrefix(subnet, types.SUBNET_ID_PREFIX):
+		case byVpcID:
+			v.addf(index(path, i), "%q must be a subnet id since vpcId references an existing vpc", subnet)
+		case names[subnet], strings.HasPrefix(subnet, types.SUBNET_ID_PREFIX):
+		default:
+			v.addf(index(path, i), "subnet %q is not declared in spec.networking", subnet)
+		}
+	}
+}
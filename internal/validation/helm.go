@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"maps"
+	"pulumi-eks/internal/types"
+	"slices"
+	"strings"
+)
+
+func (v *validator) helmCharts(path string, helm types.HelmChartsComponentes) {
+	componentsPath := field(path, "components")
+	names := make(map[string]int, len(helm.Components))
+
+	for i, component := range helm.Components {
+		componentPath := index(componentsPath, i)
+
+		if v.required(field(componentPath, "name"), component.Name) {
+			if previous, exists := names[component.Name]; exists {
+				v.addf(field(componentPath, "name"), "duplicated component name %q, already used by %s", component.Name, index(componentsPath, previous))
+			} else {
+				names[component.Name] = i
+			}
+		}
+
+		v.required(field(componentPath, "chart"), component.Chart)
+		v.required(field(componentPath, "repository"), component.Repository)
+		v.required(field(componentPath, "namespace"), component.Namespace)
+
+		if component.Version == nil {
+			v.addf(field(componentPath, "version"), "is required")
+		}
+
+		v.setValues(field(componentPath, "setValues"), component.SetValues)
+
+		oidc := component.WithOIDCProvider
+		if oidc == nil || !oidc.Create {
+			continue
+		}
+
+		oidcPath := field(componentPath, "withOidcProvider")
+		v.required(field(field(oidcPath, "role"), "name"), oidc.OidcIAMRole.Name)
+		v.required(field(field(oidcPath, "serviceAccount"), "name"), oidc.ServiceAccount.Name)
+		v.policyFiles(field(field(oidcPath, "role"), "selfManagedPoliciesPath"), oidc.OidcIAMRole.SelfManagedPoliciesPath)
+	}
+}
+
+func (v *validator) setValues(path string, values map[string]interface{}) {
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		switch value := values[key].(type) {
+		case map[string]interface{}:
+			v.setValues(field(path, key), value)
+		case string:
+			if _, file, found := strings.Cut(value, "from-file="); found {
+				v.readable(field(path, key), file)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"os"
+	"pulumi-eks/internal/types"
+)
+
+func (v *validator) identityPodAgent(path string, identity types.IdentityPodAgent) {
+	if !identity.Deploy {
+		return
+	}
+
+	rolesPath := field(field(path, "identities"), "roles")
+	roles := make(map[string]int, len(identity.Identities.Roles))
+
+	for i, role := range identity.Identities.Roles {
+		rolePath := index(rolesPath, i)
+
+		if v.required(field(rolePath, "roleName"), role.RoleName) {
+			if previous, exists := roles[role.RoleName]; exists {
+				v.addf(field(rolePath, "roleName"), "duplicated role %q, already declared by %s", role.RoleName, index(rolesPath, previous))
+			} else {
+				roles[role.RoleName] = i
+			}
+		}
+
+		v.policyFiles(field(rolePath, "selfManagedPoliciesPath"), role.SelfManagedPoliciesPath)
+	}
+
+	relationshipsPath := field(field(path, "identities"), "relationships")
+
+	for i, relationship := range identity.Identities.Relationships {
+		relationshipPath := index(relationshipsPath, i)
+
+		if v.required(field(relationshipPath, "roleName"), relationship.RoleName) {
+			if _, exists := roles[relationship.RoleName]; !exists {
+				v.addf(field(relationshipPath, "roleName"), "role %q is not declared in %s", relationship.RoleName, rolesPath)
+			}
+		}
+
+		v.required(field(relationshipPath, "namespace"), relationship.Namespace)
+	}
+}
+
+func (v *validator) policyFiles(path string, files []string) {
+	for i, file := range files {
+		v.readable(index(path, i), file)
+	}
+}
+
+func (v *validator) readable(path, file string) {
+	if _, err := os.Stat(file); err != nil {
+		v.addf(path, "file %q is not readable: %v", file, err)
+	}
+}
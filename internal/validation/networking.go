@@ -0,0 +1,567 @@
+package validation
+
+import (
+	"net/netip"
+	"pulumi-eks/internal/types"
+	"slices"
+	"strings"
+)
+
+func (v *validator) networking(path string, networking types.Networking) {
+	v.flowLogs(field(path, "flowLogs"), networking.FlowLogs)
+
+	if networking.Existing != nil {
+		v.existingNetworking(field(path, "existing"), *networking.Existing)
+
+		if len(networking.VpcEndpoints.Gateway) > 0 || len(networking.VpcEndpoints.Interface) > 0 {
+			v.addf(field(path, "vpcEndpoints"), "vpc endpoints are not supported with an existing vpc")
+		}
+
+		if networking.Connectivity.TransitGateway != nil || len(networking.Connectivity.Peerings) > 0 {
+			v.addf(field(path, "connectivity"), "connectivity is not supported with an existing vpc")
+		}
+
+		if networking.NetworkAcls != (types.NetworkAcls{}) {
+			v.addf(field(path, "networkAcls"), "network acls are not supported with an existing vpc")
+		}
+		return
+	}
+
+	v.required(field(path, "name"), networking.Name)
+
+	var vpcPrefixes []netip.Prefix
+	if v.required(field(path, "cidrBlock"), networking.CidrBlock) {
+		vpcPrefixes = append(vpcPrefixes, v.prefix(field(path, "cidrBlock"), networking.CidrBlock))
+	}
+
+	for i, cidrBlock := range networking.SecondaryCidrBlocks {
+		cidrBlockPath := index(field(path, "secondaryCidrBlocks"), i)
+
+		secondaryPrefix := v.prefix(cidrBlockPath, cidrBlock)
+		if !secondaryPrefix.IsValid() {
+			continue
+		}
+
+		for _, otherPrefix := range vpcPrefixes {
+			if otherPrefix.IsValid() && secondaryPrefix.Overlaps(otherPrefix) {
+				v.addf(cidrBlockPath, "%s overlaps with the vpc cidr block %s", secondaryPrefix, otherPrefix)
+			}
+		}
+
+		vpcPrefixes = append(vpcPrefixes, secondaryPrefix)
+	}
+
+	subnetsPath := field(path, "subnets")
+	if len(networking.Subnets) == 0 {
+		v.addf(subnetsPath, "at least one subnet is required")
+		return
+	}
+
+	names := make(map[string]int, len(networking.Subnets))
+	prefixes := make([]netip.Prefix, len(networking.Subnets))
+	publicAZs := make(map[string]bool)
+
+	for i, subnet := range networking.Subnets {
+		subnetPath := index(subnetsPath, i)
+
+		if v.required(field(subnetPath, "name"), subnet.Name) {
+			if previous, exists := names[subnet.Name]; exists {
+				v.addf(field(subnetPath, "name"), "duplicated subnet name %q, already used by %s", subnet.Name, index(subnetsPath, previous))
+			} else {
+				names[subnet.Name] = i
+			}
+		}
+
+		v.required(field(subnetPath, "availabilityZone"), subnet.AvailabilityZone)
+
+		if subnet.PublicIpOnLaunch {
+			publicAZs[subnet.AvailabilityZone] = true
+		}
+
+		if subnet.PublicIpOnLaunch && subnet.Isolated {
+			v.addf(field(subnetPath, "isolated"), "an isolated subnet cannot have publicIpOnLaunch")
+		}
+
+		if !v.required(field(subnetPath, "cidrBlock"), subnet.CidrBlock) {
+			continue
+		}
+
+		subnetPrefix := v.prefix(field(subnetPath, "cidrBlock"), subnet.CidrBlock)
+		if !subnetPrefix.IsValid() {
+			continue
+		}
+
+		if len(vpcPrefixes) > 0 && !slices.ContainsFunc(vpcPrefixes, func(vpcPrefix netip.Prefix) bool {
+			return vpcPrefix.IsValid() && containsPrefix(vpcPrefix, subnetPrefix)
+		}) {
+			v.addf(field(subnetPath, "cidrBlock"), "%s is not inside the vpc cidr blocks %v", subnetPrefix, vpcPrefixes)
+		}
+
+		for other, otherPrefix := range prefixes[:i] {
+			if otherPrefix.IsValid() && subnetPrefix.Overlaps(otherPrefix) {
+				v.addf(field(subnetPath, "cidrBlock"), "%s overlaps with %s of %s", subnetPrefix, otherPrefix, index(subnetsPath, other))
+			}
+		}
+
+		prefixes[i] = subnetPrefix
+	}
+
+	v.natGateway(path, networking, publicAZs)
+	v.podSubnets(subnetsPath, networking)
+	v.ipv6(subnetsPath, networking)
+	v.vpcEndpoints(field(path, "vpcEndpoints"), networking)
+	v.connectivity(field(path, "connectivity"), networking, vpcPrefixes)
+	v.networkAcls(field(path, "networkAcls"), networking)
+}
+
+// podSubnets checks the subnets the vpc cni custom networking gives pod ips
+// from. There is one ENIConfig per availability zone, so every zone hosting
+// nodes needs exactly one pod subnet.
+func (v *validator) podSubnets(path string, networking types.Networking) {
+	podAZs := make(map[string]int)
+
+	for i, subnet := range networking.Subnets {
+		if !subnet.Pods {
+			continue
+		}
+
+		subnetPath := index(path, i)
+
+		if subnet.PublicIpOnLaunch || subnet.Isolated {
+			v.addf(field(subnetPath, "pods"), "a pod subnet cannot be public or isolated")
+		}
+
+		if previous, exists := podAZs[subnet.AvailabilityZone]; exists {
+			v.addf(field(subnetPath, "availabilityZone"), "%s already has the pod subnet %s", subnet.AvailabilityZone, index(path, previous))
+			continue
+		}
+
+		podAZs[subnet.AvailabilityZone] = i
+	}
+
+	if len(podAZs) == 0 {
+		return
+	}
+
+	for i, subnet := range networking.Subnets {
+		if subnet.Pods || subnet.PublicIpOnLaunch || subnet.Isolated {
+			continue
+		}
+
+		if _, exists := podAZs[subnet.AvailabilityZone]; !exists {
+			v.addf(index(path, i), "private subnet %q has no pod subnet in %s for its nodes", subnet.Name, subnet.AvailabilityZone)
+		}
+	}
+}
+
+// ipv6SubnetCount is how many /64 subnets fit in the amazon provided /56.
+const ipv6SubnetCount = 256
+
+func (v *validator) ipv6(path string, networking types.Networking) {
+	if !networking.Ipv6 {
+		return
+	}
+
+	netNums := make(map[int]int, len(networking.Subnets))
+
+	for i, subnet := range networking.Subnets {
+		netNum := i
+		if subnet.Ipv6NetNum != nil {
+			netNum = *subnet.Ipv6NetNum
+		}
+
+		if netNum < 0 || netNum >= ipv6SubnetCount {
+			v.addf(field(index(path, i), "ipv6NetNum"), "must be between 0 and %d, got %d", ipv6SubnetCount-1, netNum)
+			continue
+		}
+
+		if previous, exists := netNums[netNum]; exists {
+			v.addf(field(index(path, i), "ipv6NetNum"), "ipv6 subnet number %d is already used by %s", netNum, index(path, previous))
+			continue
+		}
+
+		netNums[netNum] = i
+	}
+}
+
+var gatewayEndpointServices = map[string]bool{"s3": true, "dynamodb": true}
+
+func (v *validator) vpcEndpoints(path string, networking types.Networking) {
+	endpoints := networking.VpcEndpoints
+	services := make(map[string]string)
+
+	check := func(kind string, list []string) {
+		for i, service := range list {
+			servicePath := index(field(path, kind), i)
+
+			if !v.required(servicePath, service) {
+				continue
+			}
+
+			if previous, exists := services[service]; exists {
+				v.addf(servicePath, "duplicated vpc endpoint %q, already declared in %s", service, previous)
+				continue
+			}
+			services[service] = servicePath
+
+			if kind == "gateway" && !gatewayEndpointServices[service] {
+				v.addf(servicePath, "%q has no gateway endpoint, only s3 and dynamodb do", service)
+			}
+		}
+	}
+
+	check("gateway", endpoints.Gateway)
+	check("interface", endpoints.Interface)
+
+	if len(endpoints.Gateway) == 0 && len(endpoints.Interface) == 0 {
+		return
+	}
+
+	for _, subnet := range networking.Subnets {
+		if !subnet.PublicIpOnLaunch {
+			return
+		}
+	}
+
+	v.addf(path, "vpc endpoints require at least one private or isolated subnet")
+}
+
+var (
+	flowLogsTrafficTypes            = map[string]bool{"ALL": true, "ACCEPT": true, "REJECT": true}
+	flowLogsMaxAggregationIntervals = map[int]bool{60: true, 600: true}
+	cloudwatchRetentionInDays       = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+)
+
+func (v *validator) flowLogs(path string, flowLogs *types.FlowLogs) {
+	if flowLogs == nil {
+		return
+	}
+
+	switch flowLogs.Destination {
+	case "", types.FLOW_LOGS_DESTINATION_CLOUDWATCH:
+		if flowLogs.S3BucketArn != "" {
+			v.addf(field(path, "s3BucketArn"), "only used with the %s destination", types.FLOW_LOGS_DESTINATION_S3)
+		}
+
+		if flowLogs.RetentionInDays != 0 && !slices.Contains(cloudwatchRetentionInDays, flowLogs.RetentionInDays) {
+			v.addf(field(path, "retentionInDays"), "%d is not a cloudwatch retention, expected one of %v", flowLogs.RetentionInDays, cloudwatchRetentionInDays)
+		}
+	case types.FLOW_LOGS_DESTINATION_S3:
+		if !strings.HasPrefix(flowLogs.S3BucketArn, "arn:") {
+			v.addf(field(path, "s3BucketArn"), "a bucket arn is required with the %s destination", types.FLOW_LOGS_DESTINATION_S3)
+		}
+
+		if flowLogs.RetentionInDays != 0 {
+			v.addf(field(path, "retentionInDays"), "only used with the %s destination, set a lifecycle rule on the bucket instead", types.FLOW_LOGS_DESTINATION_CLOUDWATCH)
+		}
+	default:
+		v.addf(field(path, "destination"), "unknown flow logs destination %q, expected %s or %s",
+			flowLogs.Destination, types.FLOW_LOGS_DESTINATION_CLOUDWATCH, types.FLOW_LOGS_DESTINATION_S3,
+		)
+	}
+
+	if flowLogs.TrafficType != "" && !flowLogsTrafficTypes[flowLogs.TrafficType] {
+		v.addf(field(path, "trafficType"), "unknown traffic type %q, expected ALL, ACCEPT or REJECT", flowLogs.TrafficType)
+	}
+
+	if flowLogs.MaxAggregationInterval != 0 && !flowLogsMaxAggregationIntervals[flowLogs.MaxAggregationInterval] {
+		v.addf(field(path, "maxAggregationInterval"), "must be 60 or 600 seconds, got %d", flowLogs.MaxAggregationInterval)
+	}
+}
+
+func (v *validator) connectivity(path string, networking types.Networking, vpcPrefixes []netip.Prefix) {
+	connectivity := networking.Connectivity
+	remotePrefixes := make(map[netip.Prefix]string)
+
+	remoteCidrBlocks := func(path string, cidrBlocks []string) {
+		if len(cidrBlocks) == 0 {
+			v.addf(path, "at least one remote cidr block is required")
+			return
+		}
+
+		for i, cidrBlock := range cidrBlocks {
+			cidrBlockPath := index(path, i)
+
+			remotePrefix := v.prefix(cidrBlockPath, cidrBlock)
+			if !remotePrefix.IsValid() {
+				continue
+			}
+
+			if previous, exists := remotePrefixes[remotePrefix]; exists {
+				v.addf(cidrBlockPath, "%s is already routed by %s", remotePrefix, previous)
+				continue
+			}
+			remotePrefixes[remotePrefix] = cidrBlockPath
+
+			for _, vpcPrefix := range vpcPrefixes {
+				if vpcPrefix.IsValid() && remotePrefix.Overlaps(vpcPrefix) {
+					v.addf(cidrBlockPath, "%s overlaps with the vpc cidr block %s", remotePrefix, vpcPrefix)
+				}
+			}
+		}
+	}
+
+	if transitGateway := connectivity.TransitGateway; transitGateway != nil {
+		transitGatewayPath := field(path, "transitGateway")
+
+		if v.required(field(transitGatewayPath, "id"), transitGateway.ID) && !strings.HasPrefix(transitGateway.ID, types.TRANSIT_GATEWAY_ID_PREFIX) {
+			v.addf(field(transitGatewayPath, "id"), "%q is not a transit gateway id", transitGateway.ID)
+		}
+
+		remoteCidrBlocks(field(transitGatewayPath, "remoteCidrBlocks"), transitGateway.RemoteCidrBlocks)
+
+		if !slices.ContainsFunc(networking.Subnets, func(subnet types.Subnets) bool {
+			return !subnet.PublicIpOnLaunch && !subnet.Isolated && !subnet.Pods
+		}) {
+			v.addf(transitGatewayPath, "the transit gateway attachment requires at least one private subnet")
+		}
+	}
+
+	names := make(map[string]int, len(connectivity.Peerings))
+	peeringsPath := field(path, "peerings")
+
+	for i, peering := range connectivity.Peerings {
+		peeringPath := index(peeringsPath, i)
+
+		if v.required(field(peeringPath, "name"), peering.Name) {
+			if previous, exists := names[peering.Name]; exists {
+				v.addf(field(peeringPath, "name"), "duplicated peering name %q, already used by %s", peering.Name, index(peeringsPath, previous))
+			} else {
+				names[peering.Name] = i
+			}
+		}
+
+		if v.required(field(peeringPath, "peerVpcId"), peering.PeerVpcID) && !strings.HasPrefix(peering.PeerVpcID, types.VPC_ID_PREFIX) {
+			v.addf(field(peeringPath, "peerVpcId"), "%q is not a vpc id", peering.PeerVpcID)
+		}
+
+		remoteCidrBlocks(field(peeringPath, "remoteCidrBlocks"), peering.RemoteCidrBlocks)
+	}
+}
+
+const maxNetworkAclRuleNumber = 32766
+
+var networkAclProtocols = map[string]bool{"tcp": true, "udp": true, "icmp": true, "-1": true}
+
+func (v *validator) networkAcls(path string, networking types.Networking) {
+	tiers := []struct {
+		name   string
+		acl    *types.NetworkAcl
+		inTier func(types.Subnets) bool
+	}{
+		{"public", networking.NetworkAcls.Public, func(s types.Subnets) bool { return s.PublicIpOnLaunch }},
+		{"private", networking.NetworkAcls.Private, func(s types.Subnets) bool { return !s.PublicIpOnLaunch && !s.Isolated }},
+		{"isolated", networking.NetworkAcls.Isolated, func(s types.Subnets) bool { return s.Isolated }},
+	}
+
+	for _, tier := range tiers {
+		if tier.acl == nil {
+			continue
+		}
+
+		tierPath := field(path, tier.name)
+
+		if !slices.ContainsFunc(networking.Subnets, tier.inTier) {
+			v.addf(tierPath, "there is no %s subnet to associate the network acl to", tier.name)
+		}
+
+		v.networkAclRules(field(tierPath, "ingress"), tier.acl.Ingress)
+		v.networkAclRules(field(tierPath, "egress"), tier.acl.Egress)
+	}
+}
+
+func (v *validator) networkAclRules(path string, rules []types.NetworkAclRule) {
+	ruleNumbers := make(map[int]int, len(rules))
+
+	for i, rule := range rules {
+		rulePath := index(path, i)
+
+		if rule.RuleNumber < 1 || rule.RuleNumber > maxNetworkAclRuleNumber {
+			v.addf(field(rulePath, "ruleNumber"), "must be between 1 and %d, got %d", maxNetworkAclRuleNumber, rule.RuleNumber)
+		} else if previous, exists := ruleNumbers[rule.RuleNumber]; exists {
+			v.addf(field(rulePath, "ruleNumber"), "duplicated rule number %d, already used by %s", rule.RuleNumber, index(path, previous))
+		} else {
+			ruleNumbers[rule.RuleNumber] = i
+		}
+
+		if rule.Action != "allow" && rule.Action != "deny" {
+			v.addf(field(rulePath, "action"), "unknown action %q, expected allow or deny", rule.Action)
+		}
+
+		if !networkAclProtocols[rule.Protocol] {
+			v.addf(field(rulePath, "protocol"), "unknown protocol %q, expected tcp, udp, icmp or -1 for all", rule.Protocol)
+		}
+
+		switch {
+		case rule.CidrBlock == "" && rule.Ipv6CidrBlock == "":
+			v.addf(field(rulePath, "cidrBlock"), "either cidrBlock or ipv6CidrBlock is required")
+		case rule.CidrBlock != "" && rule.Ipv6CidrBlock != "":
+			v.addf(field(rulePath, "cidrBlock"), "cidrBlock and ipv6CidrBlock are mutually exclusive")
+		case rule.CidrBlock != "":
+			v.prefix(field(rulePath, "cidrBlock"), rule.CidrBlock)
+		default:
+			v.prefix(field(rulePath, "ipv6CidrBlock"), rule.Ipv6CidrBlock)
+		}
+
+		if rule.Protocol != "tcp" && rule.Protocol != "udp" {
+			continue
+		}
+
+		if rule.FromPort < 0 || rule.ToPort > 65535 || rule.FromPort > rule.ToPort {
+			v.addf(rulePath, "invalid port range %d-%d", rule.FromPort, rule.ToPort)
+		}
+	}
+}
+
+func (v *validator) natGateway(path string, networking types.Networking, publicAZs map[string]bool) {
+	modePath := field(field(path, "natGateway"), "mode")
+
+	switch networking.NatGateway.Mode {
+	case "", types.NAT_GATEWAY_PER_SUBNET, types.NAT_GATEWAY_PER_AZ:
+	case types.NAT_GATEWAY_NONE:
+		return
+	case types.NAT_GATEWAY_SINGLE:
+		if len(publicAZs) == 0 {
+			v.addf(modePath, "mode %s requires at least one public subnet", types.NAT_GATEWAY_SINGLE)
+		}
+		return
+	default:
+		v.addf(modePath, "unknown nat gateway mode %q, expected one of %s, %s, %s or %s",
+			networking.NatGateway.Mode,
+			types.NAT_GATEWAY_PER_SUBNET, types.NAT_GATEWAY_PER_AZ, types.NAT_GATEWAY_SINGLE, types.NAT_GATEWAY_NONE,
+		)
+		return
+	}
+
+	for i, subnet := range networking.Subnets {
+		if subnet.PublicIpOnLaunch || subnet.Isolated || subnet.AvailabilityZone == "" {
+			continue
+		}
+
+		if !publicAZs[subnet.AvailabilityZone] {
+			v.addf(index(field(path, "subnets"), i), "private subnet %q has no public subnet in %s to place its nat gateway", subnet.Name, subnet.AvailabilityZone)
+		}
+	}
+}
+
+func (v *validator) prefix(path, cidr string) netip.Prefix {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		v.addf(path, "invalid cidr block %q", cidr)
+		return netip.Prefix{}
+	}
+
+	if prefix.Masked() != prefix {
+		v.addf(path, "%s has host bits set, did you mean %s", cidr, prefix.Masked())
+	}
+
+	return prefix.Masked()
+}
+
+func containsPrefix(outer, inner netip.Prefix) bool {
+	return outer.Bits() <= inner.Bits() && outer.Contains(inner.Addr())
+}
+
+func (v *validator) existingNetworking(path string, existing types.ExistingNetworking) {
+	switch {
+	case existing.VpcID == "" && len(existing.VpcTags) == 0:
+		v.addf(field(path, "vpcId"), "either vpcId or vpcTags is required")
+	case existing.VpcID != "" && len(existing.VpcTags) > 0:
+		v.addf(field(path, "vpcId"), "vpcId and vpcTags are mutually exclusive")
+	case existing.VpcID != "" && !strings.HasPrefix(existing.VpcID, types.VPC_ID_PREFIX):
+		v.addf(field(path, "vpcId"), "%q is not a vpc id", existing.VpcID)
+	}
+
+	subnetsPath := field(path, "subnets")
+	if len(existing.Subnets) == 0 {
+		v.addf(subnetsPath, "at least one subnet is required")
+		return
+	}
+
+	names := make(map[string]int, len(existing.Subnets))
+
+	for i, subnet := range existing.Subnets {
+		subnetPath := index(subnetsPath, i)
+
+		if v.required(field(subnetPath, "name"), subnet.Name) {
+			if previous, exists := names[subnet.Name]; exists {
+				v.addf(field(subnetPath, "name"), "duplicated subnet name %q, already used by %s", subnet.Name, index(subnetsPath, previous))
+			} else {
+				names[subnet.Name] = i
+			}
+		}
+
+		switch {
+		case subnet.ID == "" && len(subnet.Tags) == 0:
+			v.addf(field(subnetPath, "id"), "either id or tags is required")
+		case subnet.ID != "" && len(subnet.Tags) > 0:
+			v.addf(field(subnetPath, "id"), "id and tags are mutually exclusive")
+		case subnet.ID != "" && !strings.HasPrefix(subnet.ID, types.SUBNET_ID_PREFIX):
+			v.addf(field(subnetPath, "id"), "%q is not a subnet id", subnet.ID)
+		}
+
+		if subnet.Public && subnet.Isolated {
+			v.addf(field(subnetPath, "isolated"), "a subnet cannot be both public and isolated")
+		}
+	}
+}
+
+// subnetNames returns the names subnets can be referenced by from the rest of
+// the spec, whether they are created or looked up.
+func subnetNames(networking types.Networking) map[string]bool {
+	names := make(map[string]bool)
+
+	if networking.Existing != nil {
+		for _, subnet := range networking.Existing.Subnets {
+			names[subnet.Name] = true
+		}
+		return names
+	}
+
+	for _, subnet := range networking.Subnets {
+		names[subnet.Name] = true
+	}
+
+	return names
+}
+
+func hasPublicSubnet(networking types.Networking) bool {
+	if networking.Existing != nil {
+		for _, subnet := range networking.Existing.Subnets {
+			if subnet.Public {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, subnet := range networking.Subnets {
+		if subnet.PublicIpOnLaunch {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasPodSubnets(networking types.Networking) bool {
+	if networking.Existing != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(networking.Subnets, func(subnet types.Subnets) bool {
+		return subnet.Pods
+	})
+}
+
+func hasPrivateSubnet(networking types.Networking) bool {
+	if networking.Existing != nil {
+		return slices.ContainsFunc(networking.Existing.Subnets, func(subnet types.ExistingSubnet) bool {
+			return !subnet.Public && !subnet.Isolated
+		})
+	}
+
+	return slices.ContainsFunc(networking.Subnets, func(subnet types.Subnets) bool {
+		return !subnet.PublicIpOnLaunch && !subnet.Isolated && !subnet.Pods
+	})
+}
@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"pulumi-eks/internal/types"
+)
+
+func (v *validator) nodeGroups(path string, nodes []types.NodeGroups, cluster types.Cluster, networking types.Networking) {
+	// node groups are always placed in the private subnets
+	if len(nodes) > 0 && !hasPrivateSubnet(networking) {
+		v.addf(path, "node groups are placed in the private subnets, none found in spec.networking")
+	}
+
+	names := make(map[string]int, len(nodes))
+
+	for i, node := range nodes {
+		nodePath := index(path, i)
+
+		if v.required(field(nodePath, "name"), node.Name) {
+			if previous, exists := names[node.Name]; exists {
+				v.addf(field(nodePath, "name"), "duplicated node group name %q, already used by %s", node.Name, index(path, previous))
+			} else {
+				names[node.Name] = i
+			}
+		}
+
+		v.required(field(nodePath, "instanceType"), node.InstanceType)
+
+		switch node.AmiFamily {
+		case "", types.AMI_FAMILY_AL2023, types.AMI_FAMILY_AL2023_NVIDIA, types.AMI_FAMILY_AL2023_ARM64, types.AMI_FAMILY_BOTTLEROCKET:
+		default:
+			v.addf(field(nodePath, "amiFamily"), "unknown ami family %q, expected %s, %s, %s or %s", node.AmiFamily,
+				types.AMI_FAMILY_AL2023, types.AMI_FAMILY_AL2023_NVIDIA, types.AMI_FAMILY_AL2023_ARM64, types.AMI_FAMILY_BOTTLEROCKET,
+			)
+		}
+
+		if node.ImageId != "" && node.AmiReleaseVersion != "" {
+			v.addf(field(nodePath, "amiReleaseVersion"), "imageId already pins the ami, set only one of them")
+		}
+
+		// a pinned ami is built for one kubernetes version, the nodes would
+		// stay on it while the control plane moves on
+		if cluster.Upgrade && node.ImageId != "" {
+			v.addf(field(nodePath, "imageId"), "must be left empty in upgrade mode so the nodes roll onto the ami of kubernetes %s", cluster.KubernetesVersion)
+		}
+
+		// releases are published per kubernetes version, the pinned one
+		// rarely exists under the new version
+		if cluster.Upgrade && node.AmiReleaseVersion != "" {
+			v.addf(field(nodePath, "amiReleaseVersion"), "must be left empty in upgrade mode so the nodes roll onto the recommended ami of kubernetes %s", cluster.KubernetesVersion)
+		}
+
+		scalingPath := field(nodePath, "scalingConfig")
+		scaling := node.ScalingConfig
+
+		if scaling.MinSize < 0 {
+			v.addf(field(scalingPath, "minSize"), "must not be negative, got %d", scaling.MinSize)
+		}
+
+		if scaling.MaxSize < 1 {
+			v.addf(field(scalingPath, "maxSize"), "must be at least 1, got %d", scaling.MaxSize)
+		}
+
+		if scaling.MinSize > scaling.MaxSize {
+			v.addf(field(scalingPath, "minSize"), "minSize %d is greater than maxSize %d", scaling.MinSize, scaling.MaxSize)
+		}
+
+		if scaling.DesiredSize < scaling.MinSize || scaling.DesiredSize > scaling.MaxSize {
+			v.addf(field(scalingPath, "desiredSize"), "desiredSize %d must be between minSize %d and maxSize %d", scaling.DesiredSize, scaling.MinSize, scaling.MaxSize)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	cfgreader "pulumi-eks/pkg/read"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretsAllowedPath matches the only places where ${secret:key} references
+// are carried as secret outputs, anywhere else they would be used verbatim.
+var secretsAllowedPath = regexp.MustCompile(`^spec\.helmChartsComponentes\.components\[\d+\]\.setValues(\.|$)`)
+
+func (v *validator) secretReferences(path string, node *yaml.Node) {
+	if node == nil || secretsAllowedPath.MatchString(path) {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			v.secretReferences(path, child)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			v.secretReferences(index(path, i), child)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.secretReferences(joinField(path, node.Content[i].Value), node.Content[i+1])
+		}
+	case yaml.ScalarNode:
+		if cfgreader.IsSecretReference(node.Value) {
+			v.addAt(node, path, "secret references are only supported in helmChartsComponentes setValues")
+		}
+	}
+}
+
+func joinField(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return field(path, name)
+}
@@ -0,0 +1,188 @@
+package validation
+
+import (
+	"fmt"
+	"pulumi-eks/internal/types"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Problem struct {
+	Path    string
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.Line == 0:
+		return fmt.Sprintf("%s: %s", p.Path, p.Message)
+	case p.File == "":
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Path, p.Message)
+	}
+
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Path, p.Message)
+}
+
+type Errors []Problem
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, problem := range e {
+		lines[i] = "  - " + problem.String()
+	}
+
+	return fmt.Sprintf("invalid config, %d problem(s) found:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+type validator struct {
+	root     *yaml.Node
+	fileOf   func(*yaml.Node) string
+	problems Errors
+}
+
+// Validate walks the whole spec and returns every problem found as Errors,
+// or nil when the config is safe to hand over to the services. The root node
+// is optional and only used to resolve line numbers, fileOf is optional too
+// and names the file a node comes from once overlays are merged in.
+func Validate(c *types.Config, root *yaml.Node, fileOf func(*yaml.Node) string) error {
+	v := &validator{root: root, fileOf: fileOf}
+
+	checks := []func(){
+		func() { v.networking("spec.networking", c.Spec.Networking) },
+		func() { v.cluster("spec.cluster", c.Spec.Cluster, c.Spec.Networking) },
+		func() { v.nodeGroups("spec.nodeGroups", c.Spec.NodeGroups, c.Spec.Cluster, c.Spec.Networking) },
+		func() { v.identityPodAgent("spec.identityPodAgent", c.Spec.IdentityPodAgent) },
+		func() { v.helmCharts("spec.helmChartsComponentes", c.Spec.HelmChartsComponentes) },
+		func() { v.addons("spec.addons", c.Spec.Addons, c.Spec) },
+		func() { v.secretReferences("", root) },
+	}
+
+	for _, check := range checks {
+		check()
+	}
+
+	if len(v.problems) > 0 {
+		return v.problems
+	}
+
+	return nil
+}
+
+func (v *validator) addf(path string, format string, args ...any) {
+	v.addAt(nodeOf(v.root, path), path, fmt.Sprintf(format, args...))
+}
+
+// addAt records a problem located at node, which may be nil when the config
+// was not read from yaml.
+func (v *validator) addAt(node *yaml.Node, path, message string) {
+	problem := Problem{
+		Path:    path,
+		Message: message,
+	}
+
+	if node != nil {
+		problem.Line = node.Line
+
+		if v.fileOf != nil {
+			problem.File = v.fileOf(node)
+		}
+	}
+
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) required(path, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.addf(path, "is required")
+		return false
+	}
+
+	return true
+}
+
+func field(path, name string) string {
+	return path + "." + name
+}
+
+func index(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// lineOf resolves a path such as spec.nodeGroups[0].scalingConfig to its line
+// in the yaml document. When the path does not exist (a missing field), the
+// line of the deepest existing ancestor is returned instead.
+func lineOf(root *yaml.Node, path string) int {
+	node := nodeOf(root, path)
+	if node == nil {
+		return 0
+	}
+
+	return node.Line
+}
+
+// nodeOf returns the node of path, or of its deepest existing ancestor.
+func nodeOf(root *yaml.Node, path string) *yaml.Node {
+	if root == nil {
+		return nil
+	}
+
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		key, indexes := splitSegment(segment)
+
+		next := mappingValue(node, key)
+		if next == nil {
+			return node
+		}
+		node = next
+
+		for _, i := range indexes {
+			if node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+				return node
+			}
+			node = node.Content[i]
+		}
+	}
+
+	return node
+}
+
+func splitSegment(segment string) (string, []int) {
+	key, rest, found := strings.Cut(segment, "[")
+	if !found {
+		return key, nil
+	}
+
+	var indexes []int
+	for _, part := range strings.Split(strings.TrimSuffix(rest, "]"), "][") {
+		i, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		indexes = append(indexes, i)
+	}
+
+	return key, indexes
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,405 @@
+package validation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pulumi-eks/internal/types"
+	cfgreader "pulumi-eks/pkg/read"
+
+	"gopkg.in/yaml.v3"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return path
+}
+
+// validateFiles decodes the files the way cmd/main.go does and returns the
+// problems found.
+func validateFiles(t *testing.T, files ...string) Errors {
+	t.Helper()
+
+	c := &types.Config{}
+
+	config := cfgreader.NewAppConfigReader()
+	if err := config.ReadFrom(files[0]).Overlay(files[1:]...).Decode(c); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	problems, _ := Validate(c, config.Node(), config.FileOf).(Errors)
+
+	return problems
+}
+
+func findProblem(t *testing.T, problems Errors, path string) Problem {
+	t.Helper()
+
+	for _, problem := range problems {
+		if problem.Path == path {
+			return problem
+		}
+	}
+
+	t.Fatalf("no problem reported for %s in %v", path, problems)
+
+	return Problem{}
+}
+
+func TestProblemNamesTheOverlayFile(t *testing.T) {
+	base := writeConfig(t, "config.yaml", "spec:\n  cluster:\n    name: dev\n    kubernetesVersion: \"1.31\"\n")
+	overlay := writeConfig(t, "Pulumi.prod.yaml", "spec:\n  cluster:\n    kubernetesVersion: latest\n")
+
+	problem := findProblem(t, validateFiles(t, base, overlay), "spec.cluster.kubernetesVersion")
+
+	expected := fmt.Sprintf("%s:3: spec.cluster.kubernetesVersion:", overlay)
+	if !strings.HasPrefix(problem.String(), expected) {
+		t.Errorf("expected %q to start with %q", problem.String(), expected)
+	}
+
+	region := findProblem(t, validateFiles(t, base, overlay), "spec.cluster.region")
+	if region.File != base {
+		t.Errorf("a missing field is reported on its base file ancestor, got %q", region.File)
+	}
+}
+
+func TestProblemString(t *testing.T) {
+	tests := []struct {
+		problem  Problem
+		expected string
+	}{
+		{Problem{Path: "spec.cluster.name", Message: "is required"}, "spec.cluster.name: is required"},
+		{Problem{Path: "spec.cluster.name", Line: 4, Message: "is required"}, "line 4: spec.cluster.name: is required"},
+		{Problem{Path: "spec.cluster.name", File: "prod.yaml", Line: 4, Message: "is required"}, "prod.yaml:4: spec.cluster.name: is required"},
+	}
+
+	for _, test := range tests {
+		if actual := test.problem.String(); actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func TestSecretReferencesOutsideSetValues(t *testing.T) {
+	var root yaml.Node
+	document := `spec:
+  cluster:
+    name: ${secret:name}
+  helmChartsComponentes:
+    components:
+      - name: grafana
+        setValues:
+          adminPassword: ${secret:grafana}
+          nested:
+            - token: Bearer ${secret:token}
+        values:
+          password: ${secret:password}
+`
+	if err := yaml.Unmarshal([]byte(document), &root); err != nil {
+		t.Fatalf("invalid yaml: %v", err)
+	}
+
+	v := &validator{root: &root}
+	v.secretReferences("", &root)
+
+	expected := Errors{
+		{Path: "spec.cluster.name", Line: 3, Message: "secret references are only supported in helmChartsComponentes setValues"},
+		{Path: "spec.helmChartsComponentes.components[0].values.password", Line: 12, Message: "secret references are only supported in helmChartsComponentes setValues"},
+	}
+
+	if !reflect.DeepEqual(v.problems, expected) {
+		t.Errorf("expected %v, got %v", expected, v.problems)
+	}
+}
+
+func TestLineOf(t *testing.T) {
+	var root yaml.Node
+	document := `spec:
+  cluster:
+    name: dev
+  nodeGroups:
+    - name: general
+      scalingConfig:
+        minSize: 1
+    - name: gpu
+      labels:
+        - a
+        - b
+`
+	if err := yaml.Unmarshal([]byte(document), &root); err != nil {
+		t.Fatalf("invalid yaml: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"spec.cluster.name", 3},
+		{"spec.nodeGroups[0].scalingConfig", 7},
+		{"spec.nodeGroups[0].scalingConfig.minSize", 7},
+		{"spec.nodeGroups[1].name", 8},
+		{"spec.nodeGroups[1].labels[1]", 11},
+		{"spec.cluster.region", 3},
+		{"spec.nodeGroups[5].name", 5},
+		{"spec.networking.vpc", 2},
+	}
+
+	for _, test := range tests {
+		if actual := lineOf(&root, test.path); actual != test.expected {
+			t.Errorf("lineOf(%q): expected %d, got %d", test.path, test.expected, actual)
+		}
+	}
+
+	if actual := lineOf(nil, "spec.cluster.name"); actual != 0 {
+		t.Errorf("expected 0 without a document, got %d", actual)
+	}
+}
+
+func TestClusterAccessPrincipalArn(t *testing.T) {
+	tests := []struct {
+		principalArn string
+		valid        bool
+	}{
+		{"arn:aws:iam::123456789012:role/sre", true},
+		{"arn:aws:iam::123456789012:role/team/sre", true},
+		{"arn:aws-cn:iam::123456789012:user/ops", true},
+		{"arn:aws-us-gov:iam::123456789012:role/sre", true},
+		{"arn:aws:iam::123456789012:group/sre", false},
+		{"arn:aws:iam::1234:role/sre", false},
+		{"arn:aws:sts::123456789012:assumed-role/sre/session", false},
+		{"role/sre", false},
+	}
+
+	for _, test := range tests {
+		v := &validator{}
+		v.clusterAccess("spec.cluster.access", types.ClusterAccess{
+			Entries: []types.AccessEntry{{PrincipalArn: test.principalArn, KubernetesGroups: []string{"sre"}}},
+		})
+
+		if valid := len(v.problems) == 0; valid != test.valid {
+			t.Errorf("%s: expected valid %t, got %v", test.principalArn, test.valid, v.problems)
+		}
+	}
+}
+
+// validConfig returns a spec without any problem, each case of
+// TestValidateRules breaking one rule of it.
+func validConfig() *types.Config {
+	return &types.Config{
+		Spec: types.Spec{
+			Networking: types.Networking{
+				Name:      "apps",
+				CidrBlock: "10.0.0.0/16",
+				Subnets: []types.Subnets{
+					{Name: "pub-1a", CidrBlock: "10.0.0.0/24", AvailabilityZone: "us-east-1a", PublicIpOnLaunch: true},
+					{Name: "priv-1a", CidrBlock: "10.0.1.0/24", AvailabilityZone: "us-east-1a"},
+				},
+			},
+			Cluster: types.Cluster{
+				Name:              "apps",
+				Region:            "us-east-1",
+				KubernetesVersion: "1.31",
+				Subnets:           []string{"pub-1a", "priv-1a"},
+			},
+			NodeGroups: []types.NodeGroups{
+				{Name: "general", InstanceType: "t3.medium", ScalingConfig: types.ScalingConfig{MinSize: 1, DesiredSize: 2, MaxSize: 3}},
+			},
+			IdentityPodAgent: types.IdentityPodAgent{
+				Deploy: true,
+				Identities: types.Identities{
+					Roles:         []types.Role{{RoleName: "app"}},
+					Relationships: []types.Relationship{{RoleName: "app", Namespace: "default"}},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	if err := Validate(validConfig(), nil, nil); err != nil {
+		t.Fatalf("expected the base config to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(c *types.Config)
+		path    string
+		message string
+	}{
+		{
+			name: "minSize greater than maxSize",
+			change: func(c *types.Config) {
+				c.Spec.NodeGroups[0].ScalingConfig = types.ScalingConfig{MinSize: 4, DesiredSize: 4, MaxSize: 3}
+			},
+			path:    "spec.nodeGroups[0].scalingConfig.minSize",
+			message: "minSize 4 is greater than maxSize 3",
+		},
+		{
+			name:    "desiredSize above maxSize",
+			change:  func(c *types.Config) { c.Spec.NodeGroups[0].ScalingConfig.DesiredSize = 5 },
+			path:    "spec.nodeGroups[0].scalingConfig.desiredSize",
+			message: "desiredSize 5 must be between minSize 1 and maxSize 3",
+		},
+		{
+			name:    "desiredSize below minSize",
+			change:  func(c *types.Config) { c.Spec.NodeGroups[0].ScalingConfig.DesiredSize = 0 },
+			path:    "spec.nodeGroups[0].scalingConfig.desiredSize",
+			message: "desiredSize 0 must be between minSize 1 and maxSize 3",
+		},
+		{
+			name:    "negative minSize",
+			change:  func(c *types.Config) { c.Spec.NodeGroups[0].ScalingConfig.MinSize = -1 },
+			path:    "spec.nodeGroups[0].scalingConfig.minSize",
+			message: "must not be negative, got -1",
+		},
+		{
+			name:    "maxSize zero",
+			change:  func(c *types.Config) { c.Spec.NodeGroups[0].ScalingConfig = types.ScalingConfig{} },
+			path:    "spec.nodeGroups[0].scalingConfig.maxSize",
+			message: "must be at least 1, got 0",
+		},
+		{
+			name: "overlapping subnet cidr blocks",
+			change: func(c *types.Config) {
+				c.Spec.Networking.Subnets[1].CidrBlock = "10.0.0.128/25"
+			},
+			path:    "spec.networking.subnets[1].cidrBlock",
+			message: "10.0.0.128/25 overlaps with 10.0.0.0/24 of spec.networking.subnets[0]",
+		},
+		{
+			name:    "subnet outside the vpc",
+			change:  func(c *types.Config) { c.Spec.Networking.Subnets[1].CidrBlock = "10.1.0.0/24" },
+			path:    "spec.networking.subnets[1].cidrBlock",
+			message: "10.1.0.0/24 is not inside the vpc cidr blocks",
+		},
+		{
+			name:    "secondary cidr block overlapping the vpc",
+			change:  func(c *types.Config) { c.Spec.Networking.SecondaryCidrBlocks = []string{"10.0.128.0/17"} },
+			path:    "spec.networking.secondaryCidrBlocks[0]",
+			message: "10.0.128.0/17 overlaps with the vpc cidr block 10.0.0.0/16",
+		},
+		{
+			name:    "cluster subnet not declared",
+			change:  func(c *types.Config) { c.Spec.Cluster.Subnets = []string{"pub-1a", "priv-1b"} },
+			path:    "spec.cluster.subnets[1]",
+			message: `subnet "priv-1b" is not declared in spec.networking`,
+		},
+		{
+			name: "cluster subnets defaulting with no public subnet",
+			change: func(c *types.Config) {
+				c.Spec.Cluster.Subnets = nil
+				c.Spec.Networking.Subnets = c.Spec.Networking.Subnets[1:]
+			},
+			path:    "spec.cluster.subnets",
+			message: "no subnets listed and no public subnet found in spec.networking to default to",
+		},
+		{
+			name: "node groups with no private subnet",
+			change: func(c *types.Config) {
+				c.Spec.Networking.Subnets = c.Spec.Networking.Subnets[:1]
+				c.Spec.Networking.NatGateway.Mode = types.NAT_GATEWAY_NONE
+				c.Spec.Cluster.Subnets = []string{"pub-1a"}
+			},
+			path:    "spec.nodeGroups",
+			message: "node groups are placed in the private subnets, none found in spec.networking",
+		},
+		{
+			name:    "relationship role not declared",
+			change:  func(c *types.Config) { c.Spec.IdentityPodAgent.Identities.Relationships[0].RoleName = "ap" },
+			path:    "spec.identityPodAgent.identities.relationships[0].roleName",
+			message: `role "ap" is not declared in spec.identityPodAgent.identities.roles`,
+		},
+		{
+			name: "duplicated role",
+			change: func(c *types.Config) {
+				c.Spec.IdentityPodAgent.Identities.Roles = append(c.Spec.IdentityPodAgent.Identities.Roles, types.Role{RoleName: "app"})
+			},
+			path:    "spec.identityPodAgent.identities.roles[1].roleName",
+			message: `duplicated role "app", already declared by spec.identityPodAgent.identities.roles[0]`,
+		},
+		{
+			name: "bastion with no egress nor ssm endpoints",
+			change: func(c *types.Config) {
+				c.Spec.Cluster.EndpointAccess = types.ENDPOINT_ACCESS_PRIVATE
+				c.Spec.Cluster.Bastion = &types.Bastion{}
+				c.Spec.Networking.NatGateway.Mode = types.NAT_GATEWAY_NONE
+				c.Spec.Networking.VpcEndpoints.Interface = []string{"ssm"}
+			},
+			path:    "spec.cluster.bastion",
+			message: "session manager cannot reach the bastion with natGateway.mode none, add the ssmmessages, ec2messages interface vpc endpoints",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := validConfig()
+			test.change(c)
+
+			problems, _ := Validate(c, nil, nil).(Errors)
+
+			problem := findProblem(t, problems, test.path)
+			if !strings.Contains(problem.Message, test.message) {
+				t.Errorf("expected %q, got %q", test.message, problem.Message)
+			}
+		})
+	}
+}
+
+func TestBastionReachable(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *types.Config)
+	}{
+		{
+			name:   "nat gateways",
+			change: func(c *types.Config) {},
+		},
+		{
+			name: "ssm endpoints",
+			change: func(c *types.Config) {
+				c.Spec.Networking.NatGateway.Mode = types.NAT_GATEWAY_NONE
+				c.Spec.Networking.VpcEndpoints.Interface = []string{"ssm", "ssmmessages", "ec2messages"}
+			},
+		},
+		{
+			name: "public subnet",
+			change: func(c *types.Config) {
+				c.Spec.Networking.NatGateway.Mode = types.NAT_GATEWAY_NONE
+				c.Spec.Cluster.Bastion.Subnet = "pub-1a"
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := validConfig()
+			c.Spec.Cluster.EndpointAccess = types.ENDPOINT_ACCESS_PRIVATE
+			c.Spec.Cluster.Bastion = &types.Bastion{}
+			test.change(c)
+
+			if err := Validate(c, nil, nil); err != nil {
+				t.Errorf("expected the bastion to be reachable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClusterSubnetNamedLikeAnId(t *testing.T) {
+	c := validConfig()
+	c.Spec.Networking.Subnets[0].Name = "subnet-apps-1a"
+	c.Spec.Cluster.Subnets = []string{"subnet-apps-1a", "subnet-0123456789abcdef0"}
+
+	if err := Validate(c, nil, nil); err != nil {
+		t.Errorf("expected subnet names starting with subnet- to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+)
+
+// Subnet carves the netNum-th subnet of newBits additional prefix bits out of
+// base, the same way terraform's cidrsubnet does. It works for both ipv4 and
+// ipv6 prefixes.
+func Subnet(base netip.Prefix, newBits int, netNum int) (netip.Prefix, error) {
+	totalBits := base.Addr().BitLen()
+	bits := base.Bits() + newBits
+
+	if newBits < 0 || bits > totalBits {
+		return netip.Prefix{}, fmt.Errorf("cannot extend %s by %d bits", base, newBits)
+	}
+
+	maxNetNum := new(big.Int).Lsh(big.NewInt(1), uint(newBits))
+	if netNum < 0 || big.NewInt(int64(netNum)).Cmp(maxNetNum) >= 0 {
+		return netip.Prefix{}, fmt.Errorf("%d subnets of /%d do not fit in %s, cannot allocate subnet number %d", maxNetNum, bits, base, netNum)
+	}
+
+	addr := new(big.Int).SetBytes(base.Masked().Addr().AsSlice())
+	offset := new(big.Int).Lsh(big.NewInt(int64(netNum)), uint(totalBits-bits))
+	addr.Add(addr, offset)
+
+	subnetAddr, ok := netip.AddrFromSlice(addr.FillBytes(make([]byte, totalBits/8)))
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("invalid address computed from %s", base)
+	}
+
+	return netip.PrefixFrom(subnetAddr, bits), nil
+}
+
+// SubnetString is Subnet for cidr strings, as they come from the config and
+// from pulumi outputs.
+func SubnetString(base string, newBits int, netNum int) (string, error) {
+	prefix, err := netip.ParsePrefix(base)
+	if err != nil {
+		return "", err
+	}
+
+	subnet, err := Subnet(prefix, newBits, netNum)
+	if err != nil {
+		return "", err
+	}
+
+	return subnet.String(), nil
+}
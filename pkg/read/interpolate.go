@@ -0,0 +1,190 @@
+package cfgreader
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	pulumiconfig "github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"gopkg.in/yaml.v3"
+)
+
+var referencePattern = regexp.MustCompile(`\$\{(env|config|secret):([^}]+)\}`)
+
+// Lookup resolves the ${env:NAME}, ${config:key} and ${secret:key} references
+// found in the config files.
+type Lookup interface {
+	Env(name string) (string, error)
+	Config(key string) (string, error)
+	Secret(key string) (pulumi.StringOutput, error)
+}
+
+type stackLookup struct {
+	ctx *pulumi.Context
+}
+
+// NewStackLookup resolves references against the process environment and the
+// current stack configuration. Keys may carry a namespace, e.g. aws:region,
+// otherwise the project namespace is used.
+func NewStackLookup(ctx *pulumi.Context) Lookup {
+	return &stackLookup{ctx: ctx}
+}
+
+func (s *stackLookup) Env(name string) (string, error) {
+	value, found := os.LookupEnv(name)
+	if !found {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
+
+func (s *stackLookup) Config(key string) (string, error) {
+	namespace, name := splitConfigKey(key)
+	return pulumiconfig.New(s.ctx, namespace).Try(name)
+}
+
+func (s *stackLookup) Secret(key string) (pulumi.StringOutput, error) {
+	namespace, name := splitConfigKey(key)
+	return pulumiconfig.New(s.ctx, namespace).TrySecret(name)
+}
+
+func splitConfigKey(key string) (string, string) {
+	if namespace, name, found := strings.Cut(key, ":"); found {
+		return namespace, name
+	}
+
+	return "", key
+}
+
+// Secrets holds the outputs of every ${secret:key} reference, by key. The
+// references are kept verbatim in the decoded config and only swapped for
+// their secret outputs by ToMap, so the plaintext never reaches the state.
+type Secrets map[string]pulumi.StringOutput
+
+// IsSecretReference reports whether value carries a ${secret:key} reference.
+func IsSecretReference(value string) bool {
+	for _, match := range referencePattern.FindAllStringSubmatch(value, -1) {
+		if match[1] == "secret" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ToMap works like pulumi.ToMap but turns strings holding secret references
+// into secret outputs, recursing into nested maps and lists.
+func (s Secrets) ToMap(values map[string]interface{}) pulumi.Map {
+	result := make(pulumi.Map, len(values))
+
+	for key, value := range values {
+		result[key] = s.toInput(value)
+	}
+
+	return result
+}
+
+func (s Secrets) toInput(value interface{}) pulumi.Input {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.ToMap(v)
+	case []interface{}:
+		array := make(pulumi.Array, len(v))
+		for i := range v {
+			array[i] = s.toInput(v[i])
+		}
+		return array
+	case string:
+		if IsSecretReference(v) {
+			return s.secretString(v)
+		}
+	}
+
+	return pulumi.ToOutput(value)
+}
+
+func (s Secrets) secretString(value string) pulumi.StringOutput {
+	var parts []interface{}
+	last := 0
+
+	for _, match := range referencePattern.FindAllStringSubmatchIndex(value, -1) {
+		source, key := value[match[2]:match[3]], value[match[4]:match[5]]
+		output, found := s[key]
+		if source != "secret" || !found {
+			continue
+		}
+
+		parts = append(parts, value[last:match[0]], output)
+		last = match[1]
+	}
+	parts = append(parts, value[last:])
+
+	return pulumi.ToSecret(
+		pulumi.Sprintf(strings.Repeat("%v", len(parts)), parts...),
+	).(pulumi.StringOutput)
+}
+
+// interpolate expands env and config references in every scalar of node and
+// registers the secret ones, leaving them in place for Secrets.ToMap.
+func interpolate(path string, node *yaml.Node, lookup Lookup, secrets Secrets) error {
+	if node.Kind == yaml.ScalarNode {
+		return interpolateScalar(path, node, lookup, secrets)
+	}
+
+	for _, child := range node.Content {
+		if err := interpolate(path, child, lookup, secrets); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func interpolateScalar(path string, node *yaml.Node, lookup Lookup, secrets Secrets) error {
+	var lookupErr error
+
+	value := referencePattern.ReplaceAllStringFunc(node.Value, func(reference string) string {
+		match := referencePattern.FindStringSubmatch(reference)
+		source, key := match[1], match[2]
+
+		var resolved string
+		var err error
+
+		switch source {
+		case "env":
+			resolved, err = lookup.Env(key)
+		case "config":
+			resolved, err = lookup.Config(key)
+		case "secret":
+			var output pulumi.StringOutput
+			if output, err = lookup.Secret(key); err == nil {
+				secrets[key] = output
+			}
+			resolved = reference
+		}
+
+		if err != nil && lookupErr == nil {
+			lookupErr = fmt.Errorf("%s:%d: cannot resolve %s: %w", path, node.Line, reference, err)
+		}
+
+		return resolved
+	})
+
+	if lookupErr != nil {
+		return lookupErr
+	}
+
+	if value != node.Value {
+		node.Value = value
+		// a reference is always written as a string, let the resolved value be
+		// decoded as whatever it looks like (bool, int) the same way yaml does.
+		if node.Style == 0 && node.Tag == "!!str" {
+			node.Tag = ""
+		}
+	}
+
+	return nil
+}
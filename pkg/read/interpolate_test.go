@@ -0,0 +1,123 @@
+package cfgreader
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type fakeLookup map[string]string
+
+func (f fakeLookup) get(key string) (string, error) {
+	value, found := f[key]
+	if !found {
+		return "", fmt.Errorf("%s is not set", key)
+	}
+
+	return value, nil
+}
+
+func (f fakeLookup) Env(name string) (string, error)   { return f.get("env:" + name) }
+func (f fakeLookup) Config(key string) (string, error) { return f.get("config:" + key) }
+
+func (f fakeLookup) Secret(key string) (pulumi.StringOutput, error) {
+	value, err := f.get("secret:" + key)
+	return pulumi.String(value).ToStringOutput(), err
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := fakeLookup{
+		"env:CLUSTER":       "prod",
+		"config:aws:region": "us-east-1",
+		"config:replicas":   "3",
+		"secret:token":      "s3cr3t",
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		expected string
+		secrets  []string
+		err      string
+	}{
+		{
+			name:     "env reference",
+			document: "name: ${env:CLUSTER}\n",
+			expected: "name: prod\n",
+		},
+		{
+			name:     "namespaced config reference inside a string",
+			document: "name: eks-${config:aws:region}-${env:CLUSTER}\n",
+			expected: "name: eks-us-east-1-prod\n",
+		},
+		{
+			name:     "resolved value decodes as what it looks like",
+			document: "replicas: ${config:replicas}\n",
+			expected: "replicas: 3\n",
+		},
+		{
+			name:     "quoted reference stays a string",
+			document: "replicas: \"${config:replicas}\"\n",
+			expected: "replicas: \"3\"\n",
+		},
+		{
+			name:     "secret reference is kept verbatim and registered",
+			document: "token: ${secret:token}\n",
+			expected: "token: ${secret:token}\n",
+			secrets:  []string{"token"},
+		},
+		{
+			name:     "unset reference",
+			document: "name: ${env:MISSING}\n",
+			err:      "config.yaml:1: cannot resolve ${env:MISSING}: env:MISSING is not set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := parseYaml(t, test.document)
+			secrets := Secrets{}
+
+			err := interpolate("config.yaml", node, lookup, secrets)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if actual := marshalYaml(t, node); actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+
+			if len(secrets) != len(test.secrets) {
+				t.Errorf("expected secrets %v, got %v", test.secrets, secrets)
+			}
+			for _, key := range test.secrets {
+				if _, found := secrets[key]; !found {
+					t.Errorf("secret %s was not registered", key)
+				}
+			}
+		})
+	}
+}
+
+func TestIsSecretReference(t *testing.T) {
+	tests := map[string]bool{
+		"${secret:token}":        true,
+		"Bearer ${secret:token}": true,
+		"${config:token}":        false,
+		"${env:TOKEN}":           false,
+		"secret:token":           false,
+	}
+
+	for value, expected := range tests {
+		if actual := IsSecretReference(value); actual != expected {
+			t.Errorf("IsSecretReference(%q): expected %t, got %t", value, expected, actual)
+		}
+	}
+}
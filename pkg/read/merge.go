@@ -0,0 +1,93 @@
+package cfgreader
+
+import "gopkg.in/yaml.v3"
+
+const mergeKey = "name"
+
+// mergeNodes deep-merges overlay onto base. Mappings are merged key by key,
+// sequences whose items all carry a name are merged item by item matching on
+// that name, and anything else in the overlay replaces the base value.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	if base == nil {
+		return overlay
+	}
+
+	if base.Kind == yaml.DocumentNode && overlay.Kind == yaml.DocumentNode {
+		if len(base.Content) == 0 {
+			return overlay
+		}
+		if len(overlay.Content) > 0 {
+			base.Content[0] = mergeNodes(base.Content[0], overlay.Content[0])
+		}
+		return base
+	}
+
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+
+			if at := mappingIndex(base, key.Value); at >= 0 {
+				base.Content[at+1] = mergeNodes(base.Content[at+1], value)
+				continue
+			}
+
+			base.Content = append(base.Content, key, value)
+		}
+		return base
+
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode &&
+		namedItems(base) && namedItems(overlay):
+		for _, item := range overlay.Content {
+			name := mappingScalar(item, mergeKey)
+
+			matched := false
+			for i, baseItem := range base.Content {
+				if mappingScalar(baseItem, mergeKey) == name {
+					base.Content[i] = mergeNodes(baseItem, item)
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+	}
+
+	return overlay
+}
+
+func namedItems(sequence *yaml.Node) bool {
+	for _, item := range sequence.Content {
+		if mappingScalar(item, mergeKey) == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func mappingScalar(mapping *yaml.Node, key string) string {
+	if mapping.Kind != yaml.MappingNode {
+		return ""
+	}
+
+	if at := mappingIndex(mapping, key); at >= 0 && mapping.Content[at+1].Kind == yaml.ScalarNode {
+		return mapping.Content[at+1].Value
+	}
+
+	return ""
+}
@@ -0,0 +1,83 @@
+package cfgreader
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseYaml(t *testing.T, document string) *yaml.Node {
+	t.Helper()
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(document), &node); err != nil {
+		t.Fatalf("invalid yaml: %v", err)
+	}
+
+	return &node
+}
+
+func marshalYaml(t *testing.T, node *yaml.Node) string {
+	t.Helper()
+
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return string(out)
+}
+
+func mergedYaml(t *testing.T, base, overlay string) string {
+	t.Helper()
+
+	return marshalYaml(t, mergeNodes(parseYaml(t, base), parseYaml(t, overlay)))
+}
+
+func TestMergeNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		overlay  string
+		expected string
+	}{
+		{
+			name:     "scalar override",
+			base:     "cluster:\n  name: dev\n  region: us-east-1\n",
+			overlay:  "cluster:\n  name: prod\n",
+			expected: "cluster:\n    name: prod\n    region: us-east-1\n",
+		},
+		{
+			name:     "new mapping key",
+			base:     "cluster:\n  name: dev\n",
+			overlay:  "cluster:\n  upgrade: true\n",
+			expected: "cluster:\n    name: dev\n    upgrade: true\n",
+		},
+		{
+			name:     "named list items merged by name",
+			base:     "nodeGroups:\n  - name: a\n    instanceType: t3.medium\n  - name: b\n    instanceType: t3.large\n",
+			overlay:  "nodeGroups:\n  - name: b\n    instanceType: m5.large\n  - name: c\n    instanceType: t3.small\n",
+			expected: "nodeGroups:\n    - name: a\n      instanceType: t3.medium\n    - name: b\n      instanceType: m5.large\n    - name: c\n      instanceType: t3.small\n",
+		},
+		{
+			name:     "unnamed list replaced",
+			base:     "subnets: [a, b]\n",
+			overlay:  "subnets: [c]\n",
+			expected: "subnets: [c]\n",
+		},
+		{
+			name:     "scalar replaces a mapping",
+			base:     "existing:\n  vpcId: vpc-1\n",
+			overlay:  "existing: null\n",
+			expected: "existing: null\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := mergedYaml(t, test.base, test.overlay); actual != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
@@ -1,14 +1,28 @@
 package cfgreader
 
 import (
+	"fmt"
 	"os"
+	"reflect"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Read struct {
+	path     string
+	file     []byte
+	overlays []overlay
+	node     yaml.Node
+	strict   bool
+	lookup   Lookup
+	secrets  Secrets
+	sources  map[*yaml.Node]string
+	err      error
+}
+
+type overlay struct {
+	path string
 	file []byte
-	err  error
 }
 
 func NewAppConfigReader() *Read { return &Read{} }
@@ -19,14 +33,123 @@ func (r *Read) ReadFrom(filePath string) *Read {
 		r.err = err
 	}
 
+	r.path = filePath
 	r.file = b
 	return r
 }
 
+// Overlay reads files to be deep-merged onto the base file, in order, when
+// decoding. See mergeNodes for the merge rules.
+func (r *Read) Overlay(filePaths ...string) *Read {
+	for _, filePath := range filePaths {
+		b, err := os.ReadFile(filePath)
+		if err != nil && r.err == nil {
+			r.err = err
+		}
+
+		r.overlays = append(r.overlays, overlay{path: filePath, file: b})
+	}
+
+	return r
+}
+
+// Strict makes Decode reject keys that do not map to any field of the target
+// type instead of silently ignoring them.
+func (r *Read) Strict() *Read {
+	r.strict = true
+	return r
+}
+
+// Interpolate expands ${env:NAME}, ${config:key} and ${secret:key}
+// references through lookup when decoding. See Secrets for the secret ones.
+func (r *Read) Interpolate(lookup Lookup) *Read {
+	r.lookup = lookup
+	r.secrets = make(Secrets)
+	return r
+}
+
 func (r *Read) Decode(v any) error {
 	if r.err != nil {
 		return r.err
 	}
 
-	return yaml.Unmarshal(r.file, v)
+	var unknown UnknownFieldsError
+
+	r.sources = make(map[*yaml.Node]string)
+
+	base, err := r.parse(r.path, r.file, v, &unknown)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range r.overlays {
+		node, err := r.parse(o.path, o.file, v, &unknown)
+		if err != nil {
+			return err
+		}
+
+		if node.Kind != 0 {
+			base = mergeNodes(base, node)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return unknown
+	}
+
+	r.node = *base
+	r.sources[&r.node] = r.sources[base]
+
+	if r.node.Kind == 0 {
+		return nil
+	}
+
+	return r.node.Decode(v)
+}
+
+func (r *Read) parse(path string, file []byte, v any, unknown *UnknownFieldsError) (*yaml.Node, error) {
+	var node yaml.Node
+
+	if err := yaml.Unmarshal(file, &node); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if r.lookup != nil {
+		if err := interpolate(path, &node, r.lookup, r.secrets); err != nil {
+			return nil, err
+		}
+	}
+
+	recordSource(r.sources, path, &node)
+
+	if r.strict && node.Kind != 0 {
+		*unknown = append(*unknown, unknownFields(path, &node, reflect.TypeOf(v), "")...)
+	}
+
+	return &node, nil
+}
+
+// Node returns the merged document node of the last Decode, used to map
+// config paths back to their line numbers.
+func (r *Read) Node() *yaml.Node {
+	return &r.node
+}
+
+// FileOf returns the file a node of the merged document was read from, the
+// base file or the overlay that last set it.
+func (r *Read) FileOf(node *yaml.Node) string {
+	return r.sources[node]
+}
+
+func recordSource(sources map[*yaml.Node]string, path string, node *yaml.Node) {
+	sources[node] = path
+
+	for _, child := range node.Content {
+		recordSource(sources, path, child)
+	}
+}
+
+// Secrets returns the secret references registered by the last Decode.
+func (r *Read) Secrets() Secrets {
+	return r.secrets
 }
@@ -0,0 +1,46 @@
+package cfgreader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return path
+}
+
+func TestDecodeRecordsTheFileOfMergedNodes(t *testing.T) {
+	base := writeFile(t, "config.yaml", "cluster:\n  name: dev\n  region: us-east-1\n")
+	overlay := writeFile(t, "prod.yaml", "cluster:\n  name: prod\n")
+
+	var config struct {
+		Cluster struct {
+			Name   string `yaml:"name"`
+			Region string `yaml:"region"`
+		} `yaml:"cluster"`
+	}
+
+	r := NewAppConfigReader().ReadFrom(base).Overlay(overlay)
+	if err := r.Decode(&config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cluster := r.Node().Content[0].Content[1]
+	name, region := cluster.Content[1], cluster.Content[3]
+
+	if file := r.FileOf(name); file != overlay || name.Line != 2 {
+		t.Errorf("name: expected %s:2, got %s:%d", overlay, file, name.Line)
+	}
+
+	if file := r.FileOf(region); file != base || region.Line != 3 {
+		t.Errorf("region: expected %s:3, got %s:%d", base, file, region.Line)
+	}
+}
@@ -0,0 +1,169 @@
+package cfgreader
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type UnknownField struct {
+	File       string
+	Line       int
+	Path       string
+	Suggestion string
+}
+
+func (u UnknownField) String() string {
+	msg := fmt.Sprintf("%s:%d: unknown field %q", u.File, u.Line, u.Path)
+	if u.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", u.Suggestion)
+	}
+
+	return msg
+}
+
+type UnknownFieldsError []UnknownField
+
+func (e UnknownFieldsError) Error() string {
+	lines := make([]string, len(e))
+	for i, field := range e {
+		lines[i] = "  - " + field.String()
+	}
+
+	return fmt.Sprintf("config has %d unknown field(s):\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// mergeTag is the tag yaml.v3 resolves the "<<" key of a merge to.
+const mergeTag = "!!merge"
+
+// unknownFields walks the yaml node against the type it is going to be
+// decoded into and reports every mapping key without a matching field.
+// Maps and interfaces accept any key and are not walked, aliases and merge
+// keys are walked through the node they point to.
+func unknownFields(file string, node *yaml.Node, t reflect.Type, path string) []UnknownField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return unknownFields(file, node.Content[0], t, path)
+
+	case yaml.AliasNode:
+		if node.Alias == nil {
+			return nil
+		}
+		return unknownFields(file, node.Alias, t, path)
+
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+
+		var unknown []UnknownField
+		for i, item := range node.Content {
+			unknown = append(unknown, unknownFields(file, item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return unknown
+
+	case yaml.MappingNode:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+
+		fields := structFields(t)
+		known := make([]string, 0, len(fields))
+		for name := range fields {
+			known = append(known, name)
+		}
+
+		var unknown []UnknownField
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := joinPath(path, key.Value)
+
+			if key.ShortTag() == mergeTag {
+				unknown = append(unknown, mergedFields(file, value, t, path)...)
+				continue
+			}
+
+			fieldType, found := fields[key.Value]
+			if !found {
+				unknown = append(unknown, UnknownField{
+					File:       file,
+					Line:       key.Line,
+					Path:       keyPath,
+					Suggestion: closest(key.Value, known),
+				})
+				continue
+			}
+
+			unknown = append(unknown, unknownFields(file, value, fieldType, keyPath)...)
+		}
+		return unknown
+	}
+
+	return nil
+}
+
+// mergedFields walks the value of a merge key, a single alias or a sequence
+// of them, as keys of the mapping holding it.
+func mergedFields(file string, value *yaml.Node, t reflect.Type, path string) []UnknownField {
+	if value.Kind != yaml.SequenceNode {
+		return unknownFields(file, value, t, path)
+	}
+
+	var unknown []UnknownField
+	for _, item := range value.Content {
+		unknown = append(unknown, unknownFields(file, item, t, path)...)
+	}
+
+	return unknown
+}
+
+// structFields mirrors the key naming rules of yaml.v3: the tag name when
+// set, the lowercased field name otherwise, flattening inline structs.
+func structFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if strings.Contains(options, "inline") && f.Type.Kind() == reflect.Struct {
+			for inlineName, inlineType := range structFields(f.Type) {
+				fields[inlineName] = inlineType
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fields[name] = f.Type
+	}
+
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,91 @@
+package cfgreader
+
+import (
+	"reflect"
+	"testing"
+)
+
+type strictChart struct {
+	Name     string            `yaml:"name"`
+	SkipCrds bool              `yaml:"skipCrds"`
+	Values   map[string]string `yaml:"values"`
+}
+
+type strictConfig struct {
+	Defaults map[string]any `yaml:"defaults"`
+	Charts   []strictChart  `yaml:"charts"`
+}
+
+func TestUnknownFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected []UnknownField
+	}{
+		{
+			name:     "known fields",
+			document: "charts:\n  - name: karpenter\n    skipCrds: true\n",
+		},
+		{
+			name:     "misspelled field suggests the known one",
+			document: "charts:\n  - name: karpenter\n    skipCirds: true\n",
+			expected: []UnknownField{{File: "config.yaml", Line: 3, Path: "charts[0].skipCirds", Suggestion: "skipCrds"}},
+		},
+		{
+			name:     "unrelated field has no suggestion",
+			document: "charts:\n  - name: karpenter\n    replicas: 2\n",
+			expected: []UnknownField{{File: "config.yaml", Line: 3, Path: "charts[0].replicas"}},
+		},
+		{
+			name:     "map keys are free",
+			document: "charts:\n  - name: karpenter\n    values:\n      anything: goes\n",
+		},
+		{
+			name:     "merge key of an alias",
+			document: "defaults:\n  chart: &chart\n    skipCrds: true\ncharts:\n  - <<: *chart\n    name: karpenter\n",
+		},
+		{
+			name:     "merge key of a sequence of aliases",
+			document: "defaults:\n  a: &a\n    skipCrds: true\n  b: &b\n    name: karpenter\ncharts:\n  - <<: [*a, *b]\n",
+		},
+		{
+			name:     "unknown field behind a merge key",
+			document: "defaults:\n  chart: &chart\n    skipCirds: true\ncharts:\n  - <<: *chart\n    name: karpenter\n",
+			expected: []UnknownField{{File: "config.yaml", Line: 3, Path: "charts[0].skipCirds", Suggestion: "skipCrds"}},
+		},
+		{
+			name:     "alias of a list item",
+			document: "defaults:\n  chart: &chart\n    name: karpenter\n    skipCirds: true\ncharts:\n  - *chart\n",
+			expected: []UnknownField{{File: "config.yaml", Line: 4, Path: "charts[0].skipCirds", Suggestion: "skipCrds"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unknown := unknownFields("config.yaml", parseYaml(t, test.document), reflect.TypeOf(&strictConfig{}), "")
+			if !reflect.DeepEqual(unknown, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, unknown)
+			}
+		})
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"name", "skipCrds", "values"}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"skipCirds", "skipCrds"},
+		{"SkipCrds", "skipCrds"},
+		{"nmae", "name"},
+		{"replicas", ""},
+	}
+
+	for _, test := range tests {
+		if actual := closest(test.name, candidates); actual != test.expected {
+			t.Errorf("closest(%q): expected %q, got %q", test.name, test.expected, actual)
+		}
+	}
+}